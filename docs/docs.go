@@ -339,6 +339,300 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/readings/aggregate": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Bucket the readings matching the filters by time, and optionally by id1, id2 and sensor_type, and compute aggregate functions over each bucket. Buckets are aligned to midnight UTC; at most 10000 are returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sensor Readings"
+                ],
+                "summary": "Aggregate sensor readings",
+                "parameters": [
+                    {
+                        "enum": [
+                            "1m",
+                            "5m",
+                            "1h",
+                            "1d"
+                        ],
+                        "type": "string",
+                        "description": "Bucket size (default: 1h)",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated functions: avg, min, max, sum, count, stddev, first, last, or a percentile such as p50, p95 or p99.9 (default: avg)",
+                        "name": "fn",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated columns to group by: id1, id2, sensor_type",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by ID1 (A-Z)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by ID2 (0-999)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Aggregated readings",
+                        "schema": {
+                            "$ref": "#/definitions/domain.AggregateResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/readings/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Import readings from a JSON array, NDJSON (one reading per line) or CSV (header row with id1, id2, sensor_type, value, timestamp and optionally unit and reading_id) body, or from a multipart \"file\" upload (requires admin privileges).\nThe format comes from the format parameter, the Content-Type or the uploaded file's extension. Rows are validated one by one and valid rows are stored in batches; the report lists the rejected rows.\nReadings whose reading_id is already stored are skipped.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sensor Readings"
+                ],
+                "summary": "Import sensor readings in bulk",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json",
+                            "ndjson",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Body format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to import",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report",
+                        "schema": {
+                            "$ref": "#/definitions/handler.BulkImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body; rows before the error were imported",
+                        "schema": {
+                            "$ref": "#/definitions/handler.BulkImportReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - Admin access required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error; rows before the error were imported",
+                        "schema": {
+                            "$ref": "#/definitions/handler.BulkImportReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/readings/export": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Stream all readings matching the filters, oldest first, as CSV, NDJSON or Parquet. The format comes from the format parameter or the Accept header (text/csv, application/x-ndjson, application/vnd.apache.parquet) and defaults to CSV.\nRows are read through a database cursor and written as they arrive, so exports of any size use constant memory. CSV exports can be imported again with POST /api/readings/bulk.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "Sensor Readings"
+                ],
+                "summary": "Export sensor readings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by ID1 (A-Z)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by ID2 (0-999)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "parquet"
+                        ],
+                        "type": "string",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Readings in the requested format",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "406": {
+                        "description": "Unsupported format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/api/readings/{id}": {
             "get": {
                 "security": [
@@ -506,33 +800,95 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "domain.AggregateBucket": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "description": "Start of the bucket",
+                    "type": "string",
+                    "example": "2024-01-15T10:00:00Z"
+                },
+                "id1": {
+                    "description": "Set when grouped by id1",
+                    "type": "string",
+                    "example": "A"
+                },
+                "id2": {
+                    "description": "Set when grouped by id2",
+                    "type": "integer",
+                    "example": 42
+                },
+                "sensor_type": {
+                    "description": "Set when grouped by sensor_type",
+                    "type": "string",
+                    "example": "temperature"
+                },
+                "values": {
+                    "description": "Result of each function; null when undefined, e.g. stddev of one reading",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "domain.AggregateResult": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "1h"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.AggregateBucket"
+                    }
+                },
+                "functions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "avg",
+                        "max",
+                        "p95"
+                    ]
+                },
+                "group_by": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "id1"
+                    ]
+                }
+            }
+        },
         "domain.PaginatedSensorReadings": {
             "type": "object",
             "properties": {
                 "data": {
-                    "description": "Array of sensor readings",
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/domain.SensorReading"
                     }
                 },
                 "page": {
-                    "description": "Current page number",
                     "type": "integer",
                     "example": 1
                 },
                 "page_size": {
-                    "description": "Items per page",
                     "type": "integer",
                     "example": 10
                 },
                 "total_items": {
-                    "description": "Total number of items",
                     "type": "integer",
                     "example": 100
                 },
                 "total_pages": {
-                    "description": "Total number of pages",
                     "type": "integer",
                     "example": 10
                 }
@@ -542,9 +898,7 @@ const docTemplate = `{
             "type": "object",
             "required": [
                 "id1",
-                "id2",
-                "sensor_type",
-                "value"
+                "sensor_type"
             ],
             "properties": {
                 "id": {
@@ -555,6 +909,7 @@ const docTemplate = `{
                 "id1": {
                     "description": "First identifier (A-Z)",
                     "type": "string",
+                    "maxLength": 10,
                     "example": "A"
                 },
                 "id2": {
@@ -564,9 +919,16 @@ const docTemplate = `{
                     "minimum": 0,
                     "example": 42
                 },
+                "reading_id": {
+                    "description": "Client-generated idempotency key; re-sent readings are stored once",
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "9f1c2a7e4b3d8e01-42"
+                },
                 "sensor_type": {
                     "description": "Type of sensor",
                     "type": "string",
+                    "maxLength": 50,
                     "example": "temperature"
                 },
                 "timestamp": {
@@ -574,6 +936,12 @@ const docTemplate = `{
                     "type": "string",
                     "example": "2024-01-15T10:30:00Z"
                 },
+                "unit": {
+                    "description": "Unit of the value",
+                    "type": "string",
+                    "maxLength": 20,
+                    "example": "°C"
+                },
                 "value": {
                     "description": "Sensor reading value",
                     "type": "number",
@@ -581,6 +949,49 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.BulkImportReport": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "why the import stopped early, if it did",
+                    "type": "string",
+                    "example": ""
+                },
+                "errors": {
+                    "description": "the first rejected rows",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.BulkRowError"
+                    }
+                },
+                "rejected": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "rows": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "stored": {
+                    "description": "includes readings skipped because their reading_id was already stored",
+                    "type": "integer",
+                    "example": 998
+                }
+            }
+        },
+        "handler.BulkRowError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid value \"abc\""
+                },
+                "row": {
+                    "type": "integer",
+                    "example": 17
+                }
+            }
+        },
         "handler.LoginRequest": {
             "type": "object",
             "required": [
@@ -4,12 +4,16 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 
 	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
 	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/handler"
@@ -23,6 +27,22 @@ func main() {
 		sensorType = "temperature"
 	}
 
+	// SENSOR_TYPES starts one generator per listed type; the first is the
+	// primary generator targeted by /config endpoints by default.
+	sensorTypes := []string{sensorType}
+	if typesStr := os.Getenv("SENSOR_TYPES"); typesStr != "" {
+		sensorTypes = nil
+		for _, t := range strings.Split(typesStr, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				sensorTypes = append(sensorTypes, t)
+			}
+		}
+		if len(sensorTypes) == 0 {
+			log.Fatalf("invalid SENSOR_TYPES: %q", typesStr)
+		}
+		sensorType = sensorTypes[0]
+	}
+
 	grpcAddr := os.Getenv("GRPC_ADDRESS")
 	if grpcAddr == "" {
 		grpcAddr = "microservice-b:9090"
@@ -33,33 +53,156 @@ func main() {
 		port = "8081"
 	}
 
+	replayDir := os.Getenv("REPLAY_DIR")
+	if replayDir == "" {
+		replayDir = "./replay"
+	}
+
 	config := &domain.GeneratorConfig{
 		FrequencyMs: 1000,
-		SensorType:  sensorType,
 	}
 
-	conn, err := grpc.Dial(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	devices := domain.DefaultFleet(10)
+	if fleetStr := os.Getenv("DEVICES"); fleetStr != "" {
+		parsed, err := domain.ParseFleet(fleetStr)
+		if err != nil || len(parsed) == 0 {
+			log.Fatalf("invalid DEVICES: %q %v", fleetStr, err)
+		}
+		devices = parsed
+	} else if countStr := os.Getenv("DEVICE_COUNT"); countStr != "" {
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count < 1 {
+			log.Fatalf("invalid DEVICE_COUNT: %q", countStr)
+		}
+		devices = domain.DefaultFleet(count)
+	}
+	// The fleet must pass the same checks as PUT /config/devices.
+	if err := validator.New().Struct(handler.UpdateDevicesRequest{Devices: devices}); err != nil {
+		log.Fatalf("invalid device fleet: %v", err)
+	}
+	config.Devices = devices
+
+	if seedStr := os.Getenv("GENERATOR_SEED"); seedStr != "" {
+		seed, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid GENERATOR_SEED: %v", err)
+		}
+		config.Seed = &seed
+	}
+	if startStr := os.Getenv("GENERATOR_START_TIME"); startStr != "" {
+		start, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			log.Fatalf("invalid GENERATOR_START_TIME: %v", err)
+		}
+		config.StartTime = &start
+	}
+
+	// BUFFER_DIR holds readings while microservice-b is unreachable; set it
+	// to an empty string to drop them instead.
+	config.Buffer = domain.BufferConfig{
+		Dir:      "./buffer",
+		MaxBytes: 256 << 20,
+		Overflow: domain.OverflowDropOldest,
+	}
+	if dir, ok := os.LookupEnv("BUFFER_DIR"); ok {
+		config.Buffer.Dir = dir
+	}
+	if maxStr := os.Getenv("BUFFER_MAX_BYTES"); maxStr != "" {
+		maxBytes, err := strconv.ParseInt(maxStr, 10, 64)
+		if err != nil || maxBytes < 0 {
+			log.Fatalf("invalid BUFFER_MAX_BYTES: %q", maxStr)
+		}
+		config.Buffer.MaxBytes = maxBytes
+	}
+	if overflow := os.Getenv("BUFFER_OVERFLOW"); overflow != "" {
+		config.Buffer.Overflow = domain.OverflowPolicy(overflow)
+		if err := validator.New().Struct(config.Buffer); err != nil {
+			log.Fatalf("invalid BUFFER_OVERFLOW: %q", overflow)
+		}
+	}
+
+	// Readings go out in batches of BATCH_SIZE, or after waiting
+	// BATCH_LINGER_MS for a batch to fill.
+	config.Batch = domain.BatchConfig{Size: 100, LingerMs: 50}
+	if sizeStr := os.Getenv("BATCH_SIZE"); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			log.Fatalf("invalid BATCH_SIZE: %q", sizeStr)
+		}
+		config.Batch.Size = size
+	}
+	if lingerStr := os.Getenv("BATCH_LINGER_MS"); lingerStr != "" {
+		linger, err := strconv.ParseInt(lingerStr, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid BATCH_LINGER_MS: %q", lingerStr)
+		}
+		config.Batch.LingerMs = linger
+	}
+	if err := validator.New().Struct(config.Batch); err != nil {
+		log.Fatalf("invalid batch config: %v", err)
+	}
+
+	for _, t := range sensorTypes {
+		if profile, ok := domain.LookupSensorProfile(t); ok {
+			log.Printf("using %s profile (%s, %v..%v)", profile.Name, profile.Unit, profile.Min, profile.Max)
+		} else {
+			log.Printf("no built-in profile for sensor type %q, using uniform 0-100 values", t)
+		}
+	}
+
+	// Keepalive pings detect a dead connection to microservice-b even while
+	// the stream is idle or sends are still being buffered by gRPC.
+	conn, err := grpc.Dial(grpcAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                20 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	)
 	if err != nil {
 		log.Fatalf("failed to connect to microservice-b: %v", err)
 	}
 	defer conn.Close()
 
-	// Initialize service and handler
-	genService := service.NewGeneratorService(conn, config)
-	configHandler := handler.NewConfigHandler(genService)
-
-	// Start generator in background
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go func() {
-		for {
-			if err := genService.StartGenerator(ctx); err != nil {
-				log.Printf("generator error: %v, retrying in 5 seconds", err)
-				time.Sleep(5 * time.Second)
-			}
+	// Initialize services and start generators in background
+	generators := service.NewGeneratorManager(ctx, conn, *config)
+	for _, t := range sensorTypes {
+		if _, err := generators.Add(domain.GeneratorSpec{SensorType: t}); err != nil {
+			log.Fatalf("failed to initialize %s generator: %v", t, err)
+		}
+	}
+	primary, _ := generators.Get("")
+
+	if scenarioFile := os.Getenv("SCENARIO_FILE"); scenarioFile != "" {
+		data, err := os.ReadFile(scenarioFile)
+		if err != nil {
+			log.Fatalf("failed to read scenario: %v", err)
+		}
+		scenario, err := domain.ParseScenario(data)
+		if err != nil {
+			log.Fatalf("failed to load scenario: %v", err)
+		}
+		if err := validator.New().Struct(scenario); err != nil {
+			log.Fatalf("invalid scenario: %v", err)
 		}
-	}()
+		if err := primary.ApplyScenario(scenario); err != nil {
+			log.Fatalf("invalid scenario: %v", err)
+		}
+		log.Printf("loaded scenario %q from %s", scenario.Name, scenarioFile)
+	}
+
+	configHandler := handler.NewConfigHandler(generators)
+	generatorsHandler := handler.NewGeneratorsHandler(generators)
+	statsHandler := handler.NewStatsHandler(generators)
+	controlHandler := handler.NewControlHandler(generators)
+	backfillService := service.NewBackfillService(conn, generators)
+	backfillHandler := handler.NewBackfillHandler(backfillService)
+	replayService := service.NewReplayService(conn, replayDir, sensorType)
+	replayHandler := handler.NewReplayHandler(replayService)
 
 	// Setup Echo server
 	e := echo.New()
@@ -70,13 +213,79 @@ func main() {
 	// Routes
 	e.PUT("/config/frequency", configHandler.UpdateFrequency)
 	e.GET("/config/frequency", configHandler.GetFrequency)
+	e.PUT("/config/load", configHandler.UpdateLoadProfile)
+	e.GET("/config/load", configHandler.GetLoadProfile)
+	e.DELETE("/config/load", configHandler.DeleteLoadProfile)
+	e.PUT("/config/model", configHandler.UpdateValueModel)
+	e.GET("/config/model", configHandler.GetValueModel)
+	e.PUT("/config/seed", configHandler.UpdateSeed)
+	e.GET("/config/seed", configHandler.GetSeed)
+	e.PUT("/config/faults", configHandler.UpdateFaults)
+	e.GET("/config/faults", configHandler.GetFaults)
+	e.PUT("/config/devices", configHandler.UpdateDevices)
+	e.GET("/config/devices", configHandler.GetDevices)
+	e.PUT("/config/correlation", configHandler.UpdateCorrelation)
+	e.GET("/config/correlation", configHandler.GetCorrelation)
+	e.PUT("/config/scenario", configHandler.UpdateScenario)
+	e.GET("/config/scenario", configHandler.GetScenario)
+	e.GET("/profiles", configHandler.GetProfiles)
+	e.GET("/generators", generatorsHandler.ListGenerators)
+	e.POST("/generators", generatorsHandler.CreateGenerator)
+	e.GET("/generators/:id", generatorsHandler.GetGenerator)
+	e.DELETE("/generators/:id", generatorsHandler.DeleteGenerator)
+	e.POST("/backfill", backfillHandler.StartBackfill)
+	e.GET("/backfill", backfillHandler.ListBackfills)
+	e.GET("/backfill/:id", backfillHandler.GetBackfill)
+	e.DELETE("/backfill/:id", backfillHandler.CancelBackfill)
+	e.POST("/replay", replayHandler.StartReplay)
+	e.GET("/replay", replayHandler.ListReplays)
+	e.GET("/replay/:id", replayHandler.GetReplay)
+	e.DELETE("/replay/:id", replayHandler.CancelReplay)
+	e.GET("/stats", statsHandler.GetStats)
+	e.POST("/control/pause", controlHandler.Pause)
+	e.POST("/control/resume", controlHandler.Resume)
+	e.POST("/control/stop", controlHandler.Stop)
 	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(200, map[string]string{
-			"status": "healthy",
-			"sensor_type": sensorType,
-		})
+		// The connection is IDLE between streams and READY while streaming;
+		// anything else means microservice-b is unreachable.
+		conn := generators.Connection()
+		status := "healthy"
+		if conn.State != "READY" && conn.State != "IDLE" {
+			status = "degraded"
+		}
+		streams := generators.Senders()
+		var reconnects uint64
+		for _, st := range streams {
+			reconnects += st.Reconnects
+		}
+		// state is shared by all generators, or "mixed" while they differ.
+		states := generators.Controls()
+		state := ""
+		for _, st := range states {
+			if state == "" {
+				state = string(st.State)
+			} else if state != string(st.State) {
+				state = "mixed"
+			}
+		}
+		resp := map[string]interface{}{
+			"status": status,
+			"state": state,
+			"states": states,
+			"generators": len(states),
+			"connection": conn,
+			"reconnects": reconnects,
+			"streams": streams,
+		}
+		if info, err := generators.Info(""); err == nil {
+			resp["sensor_type"] = info.SensorType
+			resp["unit"] = info.Unit
+			resp["seed"] = info.Seed
+			resp["deterministic"] = info.Deterministic
+		}
+		return c.JSON(200, resp)
 	})
 
-	log.Printf("Microservice A (sensor: %s) running on :%s", sensorType, port)
+	log.Printf("Microservice A (sensors: %s) running on :%s", strings.Join(sensorTypes, ", "), port)
 	e.Logger.Fatal(e.Start(":" + port))
 }
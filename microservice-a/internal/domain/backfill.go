@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// BackfillRequest asks for readings covering [From, To) at a simulated
+// interval, sent as fast as microservice-b accepts them.
+type BackfillRequest struct {
+	// Generator selects whose model, devices and faults are used; empty
+	// means the primary generator.
+	Generator  string    `json:"generator"`
+	From       time.Time `json:"from" validate:"required"`
+	To         time.Time `json:"to" validate:"required,gtfield=From"`
+	IntervalMs int64     `json:"interval_ms" validate:"required,min=1"`
+}
+
+type BackfillJob struct {
+	ID         string    `json:"id"`
+	Generator  string    `json:"generator"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	IntervalMs int64     `json:"interval_ms"`
+	State      JobState  `json:"state"`
+
+	TotalTicks     int64     `json:"total_ticks"`
+	CompletedTicks int64     `json:"completed_ticks"`
+	Progress       float64   `json:"progress"` // 0..1
+	SimulatedTime  time.Time `json:"simulated_time"`
+	ReadingsSent   uint64    `json:"readings_sent"`
+	ReadingsAcked  uint64    `json:"readings_acked"` // as reported by microservice-b when the job ends
+	// ReadingsRejected failed microservice-b's validation; Rejections
+	// describes the first of them.
+	ReadingsRejected uint64   `json:"readings_rejected"`
+	Rejections       []string `json:"rejections,omitempty"`
+
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
@@ -0,0 +1,10 @@
+package domain
+
+// BatchConfig groups readings into ReadingBatch messages on the WriteBatch
+// stream. A batch is sent once it holds Size readings or its oldest reading
+// has waited LingerMs, whichever comes first; Size 1 sends every reading on
+// its own.
+type BatchConfig struct {
+	Size     int   `json:"size" validate:"min=1,max=10000"`
+	LingerMs int64 `json:"linger_ms" validate:"min=0,max=60000"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest discards the oldest buffered readings to make room.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDropNewest discards incoming readings while the buffer is full.
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+)
+
+// BufferConfig configures the on-disk write-ahead buffer that holds readings
+// while microservice-b is unreachable. Each generator buffers in its own
+// subdirectory of Dir; an empty Dir disables buffering and unsent readings
+// are dropped.
+type BufferConfig struct {
+	Dir      string         `json:"dir"`
+	MaxBytes int64          `json:"max_bytes" validate:"min=0"`
+	Overflow OverflowPolicy `json:"overflow" validate:"omitempty,oneof=drop_oldest drop_newest"`
+}
+
+// DirFor returns the subdirectory of Dir that buffers the generator with the
+// given ID, refusing IDs that would resolve outside Dir.
+func (c BufferConfig) DirFor(id string) (string, error) {
+	dir := filepath.Join(c.Dir, id)
+	rel, err := filepath.Rel(c.Dir, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("generator id %q is not a valid buffer directory name", id)
+	}
+	return dir, nil
+}
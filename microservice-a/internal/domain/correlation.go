@@ -0,0 +1,36 @@
+package domain
+
+type CorrelationScope string
+
+const (
+	// CorrelationDevice reads the source sensor on the same (ID1, ID2) device.
+	CorrelationDevice CorrelationScope = "device"
+	// CorrelationSite averages the source sensor over all devices sharing ID1.
+	CorrelationSite CorrelationScope = "site"
+)
+
+// CorrelationTerm adds Coefficient * (source - Reference) to the value.
+type CorrelationTerm struct {
+	Source      string           `json:"source" validate:"required"` // sensor type
+	Scope       CorrelationScope `json:"scope,omitempty" validate:"omitempty,oneof=device site"`
+	Coefficient float64          `json:"coefficient"`
+	Reference   float64          `json:"reference"`
+}
+
+// CorrelationConfig ties a generator's values to other sensor types running
+// in the same process. Either linear Terms or a Formula may be given; a
+// formula takes precedence.
+//
+// Formulas are arithmetic expressions (+ - * / ^, parentheses) over:
+//   - value: this sensor's own value model output
+//   - <sensor_type>: the latest value of that sensor on the same device
+//   - site.<sensor_type>: its mean over devices sharing ID1
+//   - abs, min, max, sqrt, exp, log, sin, cos, pow, clamp(x, lo, hi)
+//
+// e.g. "value - 0.8 * (temperature - 21)". Until every referenced sensor
+// has produced a value the plain model output is used, as it is whenever
+// the result is not a finite number.
+type CorrelationConfig struct {
+	Terms   []CorrelationTerm `json:"terms,omitempty" validate:"omitempty,dive"`
+	Formula string            `json:"formula,omitempty"`
+}
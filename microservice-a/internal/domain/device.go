@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Device is one simulated sensor, identified by the (ID1, ID2) pair that
+// microservice-b stores with each reading.
+type Device struct {
+	ID1 string `json:"id1" validate:"required,alpha,uppercase"`
+	ID2 int    `json:"id2" validate:"min=0,max=999"`
+}
+
+func (d Device) String() string {
+	return fmt.Sprintf("%s:%d", d.ID1, d.ID2)
+}
+
+// DefaultFleet returns n devices A:1, B:1, ... Z:1, A:2, ...
+func DefaultFleet(n int) []Device {
+	devices := make([]Device, n)
+	for i := range devices {
+		devices[i] = Device{
+			ID1: string(rune('A' + i%26)),
+			ID2: i/26 + 1,
+		}
+	}
+	return devices
+}
+
+// ParseFleet parses a comma-separated list of ID1:ID2 pairs, e.g. "A:1,B:7".
+func ParseFleet(s string) ([]Device, error) {
+	var devices []Device
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id1, id2Str, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid device %q, expected ID1:ID2", part)
+		}
+		id2, err := strconv.Atoi(id2Str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID2 in device %q: %w", part, err)
+		}
+		devices = append(devices, Device{ID1: id1, ID2: id2})
+	}
+	return devices, nil
+}
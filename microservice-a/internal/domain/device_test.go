@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFleet(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []Device
+	}{
+		{"A:1,B:7", []Device{{"A", 1}, {"B", 7}}},
+		{" A:0 , Z:999 ,", []Device{{"A", 0}, {"Z", 999}}},
+		{"", nil},
+		// Syntax only; ID rules are checked by the validator.
+		{"a:1,AB:2000", []Device{{"a", 1}, {"AB", 2000}}},
+	} {
+		got, err := ParseFleet(tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFleet(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"A", "A:x", "A:1:2", "A:1,B", ":"} {
+		if got, err := ParseFleet(in); err == nil {
+			t.Errorf("ParseFleet(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestDefaultFleet(t *testing.T) {
+	fleet := DefaultFleet(28)
+	if len(fleet) != 28 {
+		t.Fatalf("DefaultFleet(28) has %d devices", len(fleet))
+	}
+	for i, want := range map[int]Device{0: {"A", 1}, 25: {"Z", 1}, 26: {"A", 2}, 27: {"B", 2}} {
+		if fleet[i] != want {
+			t.Errorf("device %d = %v, want %v", i, fleet[i], want)
+		}
+	}
+}
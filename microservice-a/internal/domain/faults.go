@@ -0,0 +1,52 @@
+package domain
+
+// FaultConfig controls fault injection. Each probability is evaluated per
+// reading; a zero value disables the fault.
+type FaultConfig struct {
+	DropProbability float64 `json:"drop_probability" validate:"min=0,max=1"`
+
+	// StuckProbability starts a stuck-at period in which the last value is
+	// repeated for StuckReadings readings.
+	StuckProbability float64 `json:"stuck_probability" validate:"min=0,max=1"`
+	StuckReadings    int     `json:"stuck_readings" validate:"min=0"`
+
+	// SpikeProbability replaces the value with an outlier SpikeMagnitude
+	// away from it, in a random direction.
+	SpikeProbability float64 `json:"spike_probability" validate:"min=0,max=1"`
+	SpikeMagnitude   float64 `json:"spike_magnitude" validate:"min=0"`
+
+	NaNProbability float64 `json:"nan_probability" validate:"min=0,max=1"`
+	InfProbability float64 `json:"inf_probability" validate:"min=0,max=1"`
+
+	// DuplicateProbability sends a reading twice. The copy gets its own
+	// reading_id, so both are stored, unless DuplicateReuseKey is set: then
+	// it carries the original's key, as a retry would, and is stored once.
+	DuplicateProbability float64 `json:"duplicate_probability" validate:"min=0,max=1"`
+	DuplicateReuseKey    bool    `json:"duplicate_reuse_key"`
+
+	// OutOfOrderProbability holds a reading back and sends it after the next one.
+	OutOfOrderProbability float64 `json:"out_of_order_probability" validate:"min=0,max=1"`
+
+	// SkewProbability shifts the timestamp up to MaxSkewSeconds into the
+	// past or future.
+	SkewProbability float64 `json:"skew_probability" validate:"min=0,max=1"`
+	MaxSkewSeconds  float64 `json:"max_skew_seconds" validate:"min=0"`
+
+	// MalformedIDProbability emits an ID1/ID2 that violates the
+	// A-Z / 0-999 constraints of microservice-b.
+	MalformedIDProbability float64 `json:"malformed_id_probability" validate:"min=0,max=1"`
+}
+
+type FaultKind string
+
+const (
+	FaultDrop        FaultKind = "drop"
+	FaultStuck       FaultKind = "stuck"
+	FaultSpike       FaultKind = "spike"
+	FaultNaN         FaultKind = "nan"
+	FaultInf         FaultKind = "inf"
+	FaultDuplicate   FaultKind = "duplicate"
+	FaultOutOfOrder  FaultKind = "out_of_order"
+	FaultSkew        FaultKind = "skew"
+	FaultMalformedID FaultKind = "malformed_id"
+)
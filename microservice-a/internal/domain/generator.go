@@ -1,6 +1,77 @@
 package domain
 
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultLogicalStart is the first timestamp of a seeded run when no start
+// time is configured.
+var DefaultLogicalStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
 type GeneratorConfig struct {
-	FrequencyMs int64  `json:"frequency_ms" validate:"required,min=100"`
-	SensorType  string `json:"sensor_type"`
+	ID          string   `json:"id"`
+	FrequencyMs int64    `json:"frequency_ms" validate:"required,min=1"`
+	SensorType  string   `json:"sensor_type"`
+	Devices     []Device `json:"devices" validate:"dive"`
+
+	// Seed makes the reading sequence reproducible. When set, timestamps
+	// come from a logical clock starting at StartTime.
+	Seed      *int64     `json:"seed,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+
+	Buffer BufferConfig `json:"buffer"`
+	Batch  BatchConfig  `json:"batch"`
+}
+
+// GeneratorSpec describes a generator created through POST /generators.
+// Unset fields fall back to the process defaults (DEVICES, GENERATOR_SEED, ...).
+type GeneratorSpec struct {
+	// ID defaults to the sensor type; see ValidateGeneratorID.
+	ID          string `json:"id"`
+	SensorType  string `json:"sensor_type" validate:"required,max=50"`
+	FrequencyMs int64  `json:"frequency_ms,omitempty" validate:"omitempty,min=1"`
+	// RatePerSecond targets readings per second across the fleet instead
+	// of a fixed FrequencyMs.
+	RatePerSecond float64            `json:"rate_per_second,omitempty" validate:"omitempty,gt=0,excluded_with=FrequencyMs"`
+	Load          *LoadProfile       `json:"load,omitempty"`
+	ValueModel    *ValueModelConfig  `json:"value_model,omitempty"`
+	Devices       []Device           `json:"devices,omitempty" validate:"omitempty,dive"`
+	DeviceCount   int                `json:"device_count,omitempty" validate:"min=0"`
+	Faults        *FaultConfig       `json:"faults,omitempty"`
+	Correlation   *CorrelationConfig `json:"correlation,omitempty"`
+	Batch         *BatchConfig       `json:"batch,omitempty"`
+	Seed          *int64             `json:"seed,omitempty"`
+	StartTime     *time.Time         `json:"start_time,omitempty"`
+}
+
+// generatorIDPattern matches IDs that are safe in URLs and as buffer
+// directory names.
+var generatorIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidateGeneratorID checks the ID a generator runs under, whether given
+// explicitly or taken from its sensor type.
+func ValidateGeneratorID(id string) error {
+	if !generatorIDPattern.MatchString(id) {
+		return fmt.Errorf("generator id %q must be 1-64 letters, digits, '_' or '-'", id)
+	}
+	return nil
+}
+
+// ValueModelFor returns the value model for a sensor type: the built-in
+// profile, or uniform 0-100 noise.
+func (c *GeneratorConfig) ValueModelFor(sensorType string) ValueModelConfig {
+	if p, ok := LookupSensorProfile(sensorType); ok {
+		return p.ValueModel
+	}
+	return FallbackValueModel()
+}
+
+// UnitFor returns the unit of a sensor type, or "" for unknown types.
+func (c *GeneratorConfig) UnitFor(sensorType string) string {
+	if p, ok := LookupSensorProfile(sensorType); ok {
+		return p.Unit
+	}
+	return ""
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateGeneratorID(t *testing.T) {
+	for _, id := range []string{"temperature", "line-2", "Press_01"} {
+		if err := ValidateGeneratorID(id); err != nil {
+			t.Errorf("ValidateGeneratorID(%q) = %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "../../tmp/x", "a/b", "a b", "..", "x?y", string(make([]byte, 65))} {
+		if err := ValidateGeneratorID(id); err == nil {
+			t.Errorf("ValidateGeneratorID(%q) accepted", id)
+		}
+	}
+}
+
+func TestBufferDirFor(t *testing.T) {
+	root := filepath.Join("var", "buffer")
+	cfg := BufferConfig{Dir: root}
+	dir, err := cfg.DirFor("temperature")
+	if err != nil || dir != filepath.Join(root, "temperature") {
+		t.Errorf("DirFor(temperature) = %q, %v", dir, err)
+	}
+	for _, id := range []string{"", ".", "..", "../x", "../../tmp/x"} {
+		if dir, err := cfg.DirFor(id); err == nil {
+			t.Errorf("DirFor(%q) = %q, want an error", id, dir)
+		}
+	}
+}
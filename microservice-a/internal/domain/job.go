@@ -0,0 +1,11 @@
+package domain
+
+// JobState is the lifecycle state of a background job (backfill, replay).
+type JobState string
+
+const (
+	JobRunning   JobState = "running"
+	JobCompleted JobState = "completed"
+	JobCancelled JobState = "cancelled"
+	JobFailed    JobState = "failed"
+)
@@ -0,0 +1,64 @@
+package domain
+
+type LoadProfileType string
+
+const (
+	LoadRamp    LoadProfileType = "ramp"
+	LoadSteps   LoadProfileType = "steps"
+	LoadBurst   LoadProfileType = "burst"
+	LoadDiurnal LoadProfileType = "diurnal"
+	LoadWindows LoadProfileType = "windows"
+)
+
+// LoadProfile varies a generator's frequency over time so ingestion can be
+// load-tested with shaped traffic. Only the fields relevant to the selected
+// Type are used. Ramp, steps and burst are timed from when the profile is
+// applied; diurnal and windows follow the wall-clock time in Timezone.
+type LoadProfile struct {
+	Type LoadProfileType `json:"type" validate:"required,oneof=ramp steps burst diurnal windows"`
+
+	// Repeat restarts a ramp or step sequence once it has finished instead
+	// of holding its last frequency.
+	Repeat bool `json:"repeat,omitempty"`
+	// Timezone is an IANA name used by diurnal and windows; default UTC.
+	Timezone string `json:"timezone,omitempty"`
+
+	// Ramp: the rate changes linearly from FromMs to ToMs over DurationSeconds.
+	FromMs          int64   `json:"from_ms,omitempty" validate:"omitempty,min=1"`
+	ToMs            int64   `json:"to_ms,omitempty" validate:"omitempty,min=1"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty" validate:"min=0"`
+
+	// Steps: each frequency is held for its duration, in order.
+	Steps []LoadStep `json:"steps,omitempty" validate:"omitempty,dive"`
+
+	// BaseMs is the frequency outside bursts and windows.
+	BaseMs int64 `json:"base_ms,omitempty" validate:"omitempty,min=1"`
+
+	// Burst: BurstMs for the first BurstSeconds of every PeriodSeconds.
+	BurstMs       int64   `json:"burst_ms,omitempty" validate:"omitempty,min=1"`
+	BurstSeconds  float64 `json:"burst_seconds,omitempty" validate:"min=0"`
+	PeriodSeconds float64 `json:"period_seconds,omitempty" validate:"min=0"`
+
+	// Diurnal: the rate follows a 24h cosine, reaching PeakMs at PeakHour
+	// and TroughMs twelve hours later.
+	PeakMs   int64   `json:"peak_ms,omitempty" validate:"omitempty,min=1"`
+	TroughMs int64   `json:"trough_ms,omitempty" validate:"omitempty,min=1"`
+	PeakHour float64 `json:"peak_hour,omitempty" validate:"min=0,lt=24"`
+
+	// Windows: the first window whose cron expression matches the current
+	// minute sets the frequency.
+	Windows []LoadWindow `json:"windows,omitempty" validate:"omitempty,dive"`
+}
+
+type LoadStep struct {
+	FrequencyMs     int64   `json:"frequency_ms" validate:"required,min=1"`
+	DurationSeconds float64 `json:"duration_seconds" validate:"gt=0"`
+}
+
+// LoadWindow is active during every minute matched by Cron, a standard
+// five-field expression (minute hour day-of-month month day-of-week), e.g.
+// "* 9-17 * * mon-fri" for business hours.
+type LoadWindow struct {
+	Cron        string `json:"cron" validate:"required"`
+	FrequencyMs int64  `json:"frequency_ms" validate:"required,min=1"`
+}
@@ -0,0 +1,75 @@
+package domain
+
+import "sort"
+
+// SensorProfile describes the physical characteristics of a sensor type:
+// its unit, the valid value range and the value model used by default.
+type SensorProfile struct {
+	Name       string           `json:"name"`
+	Unit       string           `json:"unit"`
+	Min        float64          `json:"min"`
+	Max        float64          `json:"max"`
+	ValueModel ValueModelConfig `json:"value_model"`
+}
+
+var sensorProfiles = map[string]SensorProfile{
+	"temperature": {
+		Name: "temperature", Unit: "°C", Min: -40, Max: 85,
+		ValueModel: ValueModelConfig{Type: ValueModelSine, Baseline: 21, Amplitude: 6, PeriodSeconds: 86400, Noise: 0.3},
+	},
+	"humidity": {
+		Name: "humidity", Unit: "%RH", Min: 0, Max: 100,
+		ValueModel: ValueModelConfig{Type: ValueModelRandomWalk, Baseline: 45, Noise: 0.4},
+	},
+	"pressure": {
+		Name: "pressure", Unit: "hPa", Min: 870, Max: 1085,
+		ValueModel: ValueModelConfig{Type: ValueModelRandomWalk, Baseline: 1013.25, Noise: 0.2},
+	},
+	"co2": {
+		Name: "co2", Unit: "ppm", Min: 400, Max: 5000,
+		ValueModel: ValueModelConfig{Type: ValueModelRandomWalk, Baseline: 450, Noise: 5},
+	},
+	"vibration": {
+		Name: "vibration", Unit: "mm/s", Min: 0, Max: 50,
+		ValueModel: ValueModelConfig{Type: ValueModelGaussian, Baseline: 2.5, Noise: 0.8},
+	},
+	"voltage": {
+		Name: "voltage", Unit: "V", Min: 0, Max: 260,
+		ValueModel: ValueModelConfig{Type: ValueModelGaussian, Baseline: 230, Noise: 1.5},
+	},
+	"current": {
+		Name: "current", Unit: "A", Min: 0, Max: 100,
+		ValueModel: ValueModelConfig{Type: ValueModelSine, Baseline: 12, Amplitude: 6, PeriodSeconds: 86400, Noise: 0.5},
+	},
+	"light": {
+		Name: "light", Unit: "lx", Min: 0, Max: 100000,
+		ValueModel: ValueModelConfig{Type: ValueModelSine, Baseline: 20000, Amplitude: 30000, PeriodSeconds: 86400, Noise: 200},
+	},
+}
+
+// LookupSensorProfile returns the built-in profile for a sensor type. The
+// returned value model is clamped to the profile's range.
+func LookupSensorProfile(name string) (SensorProfile, bool) {
+	p, ok := sensorProfiles[name]
+	if !ok {
+		return SensorProfile{}, false
+	}
+	if p.ValueModel.Min == nil {
+		p.ValueModel.Min = float64Ptr(p.Min)
+	}
+	if p.ValueModel.Max == nil {
+		p.ValueModel.Max = float64Ptr(p.Max)
+	}
+	return p, true
+}
+
+// SensorProfiles returns the built-in catalog sorted by name.
+func SensorProfiles() []SensorProfile {
+	profiles := make([]SensorProfile, 0, len(sensorProfiles))
+	for name := range sensorProfiles {
+		p, _ := LookupSensorProfile(name)
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
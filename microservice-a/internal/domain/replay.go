@@ -0,0 +1,68 @@
+package domain
+
+import "time"
+
+type ReplayFormat string
+
+const (
+	ReplayCSV    ReplayFormat = "csv"
+	ReplayNDJSON ReplayFormat = "ndjson"
+)
+
+type ReplayPacing string
+
+const (
+	// ReplayOriginal reproduces the gaps between recorded timestamps.
+	ReplayOriginal ReplayPacing = "original"
+	// ReplaySpeed divides the recorded gaps by Speed.
+	ReplaySpeed ReplayPacing = "speed"
+	// ReplayFast sends as fast as microservice-b accepts.
+	ReplayFast ReplayPacing = "fast"
+)
+
+// ReplayColumns maps reading fields to CSV header names or NDJSON keys.
+// Empty names fall back to the field name (value, sensor_type, ...).
+type ReplayColumns struct {
+	Value      string `json:"value"`
+	SensorType string `json:"sensor_type"`
+	ID1        string `json:"id1"`
+	ID2        string `json:"id2"`
+	Timestamp  string `json:"timestamp"`
+	Unit       string `json:"unit"`
+}
+
+// ReplayRequest streams a recorded trace through IngestService.Write.
+type ReplayRequest struct {
+	// Path is relative to the replay directory (REPLAY_DIR).
+	Path   string       `json:"path" validate:"required"`
+	Format ReplayFormat `json:"format" validate:"omitempty,oneof=csv ndjson"` // detected from the extension when empty
+
+	Columns ReplayColumns `json:"columns"`
+	// SensorType is used for rows without a sensor_type column.
+	SensorType string `json:"sensor_type"`
+
+	Pacing ReplayPacing `json:"pacing" validate:"omitempty,oneof=original speed fast"`
+	Speed  float64      `json:"speed" validate:"required_if=Pacing speed,omitempty,gt=0"`
+	Loop   bool         `json:"loop"`
+	// ShiftTimestamps rebases recorded timestamps onto the replay start so
+	// that loops continue forward in time instead of repeating.
+	ShiftTimestamps bool `json:"shift_timestamps"`
+}
+
+type ReplayJob struct {
+	ID      string        `json:"id"`
+	Request ReplayRequest `json:"request"`
+	State   JobState      `json:"state"`
+
+	ReadingsSent  uint64 `json:"readings_sent"`
+	ReadingsAcked uint64 `json:"readings_acked"` // as reported by microservice-b when the job ends
+	Loops         int    `json:"loops"`          // completed passes over the file
+	// ReadingsRejected failed microservice-b's validation; Rejections
+	// describes the first of them.
+	ReadingsRejected uint64   `json:"readings_rejected"`
+	Rejections       []string `json:"rejections,omitempty"`
+
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
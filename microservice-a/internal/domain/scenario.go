@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Scenario declaratively describes a generator run. Unset sections keep
+// their defaults: the sensor profile's value model, the configured device
+// fleet, no faults and a fixed frequency.
+type Scenario struct {
+	Name        string `json:"name"`
+	SensorType  string `json:"sensor_type"`
+	FrequencyMs int64  `json:"frequency_ms" validate:"omitempty,min=1"`
+	// RatePerSecond targets readings per second across the fleet instead
+	// of a fixed FrequencyMs.
+	RatePerSecond float64 `json:"rate_per_second,omitempty" validate:"omitempty,gt=0,excluded_with=FrequencyMs"`
+	// Load shapes the frequency over time and takes precedence over FrequencyMs.
+	Load *LoadProfile `json:"load,omitempty"`
+
+	Seed      *int64     `json:"seed,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+
+	ValueModel  *ValueModelConfig  `json:"value_model,omitempty"`
+	Devices     []Device           `json:"devices,omitempty" validate:"omitempty,dive"`
+	DeviceCount int                `json:"device_count,omitempty" validate:"min=0"`
+	Faults      *FaultConfig       `json:"faults,omitempty"`
+	Correlation *CorrelationConfig `json:"correlation,omitempty"`
+	Schedule    *Schedule          `json:"schedule,omitempty"`
+}
+
+// Schedule is a sequence of phases that change frequency (fixed or shaped
+// by a load profile) and faults over wall-clock time. After the last phase
+// its settings stay in effect unless Loop is set.
+type Schedule struct {
+	Loop   bool            `json:"loop"`
+	Phases []SchedulePhase `json:"phases" validate:"required,min=1,dive"`
+}
+
+type SchedulePhase struct {
+	Name            string       `json:"name"`
+	DurationSeconds float64      `json:"duration_seconds" validate:"gt=0"`
+	FrequencyMs     int64        `json:"frequency_ms,omitempty" validate:"omitempty,min=1"`
+	Load            *LoadProfile `json:"load,omitempty"`
+	Faults          *FaultConfig `json:"faults,omitempty"`
+}
+
+// ParseScenario decodes a scenario from YAML or JSON. Field names are the
+// JSON names in both formats and unknown fields are rejected.
+func ParseScenario(data []byte) (*Scenario, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	normalized, err := yamlToJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.DisallowUnknownFields()
+	sc := new(Scenario)
+	if err := dec.Decode(sc); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return sc, nil
+}
+
+// yamlToJSON converts the map[interface{}]interface{} values produced by
+// yaml.v2 into types encoding/json can marshal.
+func yamlToJSON(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid scenario: non-string key %v", k)
+			}
+			converted, err := yamlToJSON(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = converted
+		}
+		return m, nil
+	case []interface{}:
+		for i, val := range v {
+			converted, err := yamlToJSON(val)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,55 @@
+package domain
+
+type ValueModelType string
+
+const (
+	ValueModelUniform    ValueModelType = "uniform"
+	ValueModelSine       ValueModelType = "sine"
+	ValueModelGaussian   ValueModelType = "gaussian"
+	ValueModelRandomWalk ValueModelType = "random_walk"
+	ValueModelDrift      ValueModelType = "drift"
+	ValueModelStep       ValueModelType = "step"
+)
+
+// ValueModelConfig describes how values are produced for a sensor type.
+// Only the fields relevant to the selected Type are used.
+type ValueModelConfig struct {
+	Type ValueModelType `json:"type" validate:"required,oneof=uniform sine gaussian random_walk drift step"`
+
+	// Baseline is the centre value for sine, gaussian, drift and step models
+	// and the starting point of a random walk.
+	Baseline float64 `json:"baseline"`
+	// Noise is the standard deviation of Gaussian noise added to the signal.
+	// For random_walk it is the standard deviation of each step.
+	Noise float64 `json:"noise" validate:"min=0"`
+
+	// Sine: Amplitude and PeriodSeconds (86400 gives a diurnal cycle).
+	Amplitude     float64 `json:"amplitude,omitempty"`
+	PeriodSeconds float64 `json:"period_seconds,omitempty" validate:"min=0"`
+
+	// Drift: change of the baseline per second.
+	DriftPerSecond float64 `json:"drift_per_second,omitempty"`
+
+	// Step: probability per reading of a level change of up to StepSize.
+	StepSize        float64 `json:"step_size,omitempty" validate:"min=0"`
+	StepProbability float64 `json:"step_probability,omitempty" validate:"min=0,max=1"`
+
+	// Min and Max bound uniform and random_walk output and clamp the rest
+	// when set.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// FallbackValueModel reproduces the original uniform 0-100 output and is
+// used for sensor types without a configured model.
+func FallbackValueModel() ValueModelConfig {
+	return ValueModelConfig{
+		Type: ValueModelUniform,
+		Min:  float64Ptr(0),
+		Max:  float64Ptr(100),
+	}
+}
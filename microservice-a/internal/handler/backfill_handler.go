@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/service"
+)
+
+type BackfillHandler struct {
+	service   *service.BackfillService
+	validator *validator.Validate
+}
+
+func NewBackfillHandler(service *service.BackfillService) *BackfillHandler {
+	return &BackfillHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// POST /backfill
+func (h *BackfillHandler) StartBackfill(c echo.Context) error {
+	req := new(domain.BackfillRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	job, err := h.service.Start(*req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GET /backfill
+func (h *BackfillHandler) ListBackfills(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"jobs": h.service.List(),
+	})
+}
+
+// GET /backfill/:id
+func (h *BackfillHandler) GetBackfill(c echo.Context) error {
+	job, ok := h.service.Get(c.Param("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "backfill job not found"})
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+// DELETE /backfill/:id
+func (h *BackfillHandler) CancelBackfill(c echo.Context) error {
+	job, ok := h.service.Cancel(c.Param("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "backfill job not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"job":     job,
+		"message": "backfill job stopped",
+	})
+}
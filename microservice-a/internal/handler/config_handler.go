@@ -1,31 +1,49 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
 	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/service"
 )
 
+// ConfigHandler configures one generator per request, selected with the
+// "generator" query parameter and defaulting to the primary generator.
 type ConfigHandler struct {
-	service   *service.GeneratorService
-	validator *validator.Validate
+	generators *service.GeneratorManager
+	validator  *validator.Validate
 }
 
-func NewConfigHandler(service *service.GeneratorService) *ConfigHandler {
+func NewConfigHandler(generators *service.GeneratorManager) *ConfigHandler {
 	return &ConfigHandler{
-		service:   service,
-		validator: validator.New(),
+		generators: generators,
+		validator:  validator.New(),
 	}
 }
 
+func (h *ConfigHandler) generator(c echo.Context) (*service.GeneratorService, error) {
+	return h.generators.Get(c.QueryParam("generator"))
+}
+
+// UpdateFrequencyRequest sets either a fixed interval or a target rate in
+// readings per second across the fleet, which allows sub-millisecond ticks.
 type UpdateFrequencyRequest struct {
-	FrequencyMs int64 `json:"frequency_ms" validate:"required,min=100"`
+	FrequencyMs   int64   `json:"frequency_ms" validate:"required_without=RatePerSecond,omitempty,min=1"`
+	RatePerSecond float64 `json:"rate_per_second" validate:"required_without=FrequencyMs,omitempty,gt=0,excluded_with=FrequencyMs"`
 }
 
 // PUT /config/frequency
 func (h *ConfigHandler) UpdateFrequency(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
 	req := new(UpdateFrequencyRequest)
 	if err := c.Bind(req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
@@ -35,7 +53,15 @@ func (h *ConfigHandler) UpdateFrequency(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	h.service.UpdateFrequency(req.FrequencyMs)
+	if req.RatePerSecond > 0 {
+		gen.SetRate(req.RatePerSecond)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"rate_per_second": req.RatePerSecond,
+			"message":         "rate updated successfully",
+		})
+	}
+
+	gen.UpdateFrequency(req.FrequencyMs)
 	
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"frequency_ms": req.FrequencyMs,
@@ -45,7 +71,351 @@ func (h *ConfigHandler) UpdateFrequency(c echo.Context) error {
 
 // GET /config/frequency
 func (h *ConfigHandler) GetFrequency(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	interval := gen.CurrentInterval()
+	resp := map[string]interface{}{
+		"frequency_ms": interval.Milliseconds(),
+		"interval_us":  float64(interval) / float64(time.Microsecond),
+	}
+	if rate := gen.GetRate(); rate > 0 {
+		resp["rate_per_second"] = rate
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// PUT /config/load
+func (h *ConfigHandler) UpdateLoadProfile(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	req := new(domain.LoadProfile)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := gen.SetLoadProfile(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"load":    gen.GetLoadProfile(),
+		"message": "load profile updated successfully",
+	})
+}
+
+// GET /config/load
+func (h *ConfigHandler) GetLoadProfile(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, gen.GetLoadProfile())
+}
+
+// DELETE /config/load
+//
+// Stops shaping; the current frequency stays in effect.
+func (h *ConfigHandler) DeleteLoadProfile(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	gen.SetLoadProfile(nil)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"frequency_ms": gen.GetFrequency(),
+		"message":      "load profile removed",
+	})
+}
+
+// PUT /config/model
+func (h *ConfigHandler) UpdateValueModel(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	req := new(domain.ValueModelConfig)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := gen.UpdateValueModel(*req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"frequency_ms": h.service.GetFrequency(),
+		"value_model": req,
+		"message":     "value model updated successfully",
 	})
+}
+
+// GET /config/model
+func (h *ConfigHandler) GetValueModel(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"value_model": gen.GetValueModel(),
+	})
+}
+
+// GET /profiles
+func (h *ConfigHandler) GetProfiles(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"profiles": domain.SensorProfiles(),
+	})
+}
+
+type UpdateSeedRequest struct {
+	Seed      *int64     `json:"seed"`
+	StartTime *time.Time `json:"start_time"`
+}
+
+// PUT /config/seed
+func (h *ConfigHandler) UpdateSeed(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	req := new(UpdateSeedRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	start := domain.DefaultLogicalStart
+	if req.StartTime != nil {
+		start = *req.StartTime
+	}
+
+	if err := gen.SetSeed(req.Seed, start); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	seed, deterministic := gen.GetSeed()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"seed":          seed,
+		"deterministic": deterministic,
+		"message":       "seed updated successfully",
+	})
+}
+
+// GET /config/seed
+func (h *ConfigHandler) GetSeed(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	seed, deterministic := gen.GetSeed()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"seed":          seed,
+		"deterministic": deterministic,
+	})
+}
+
+// PUT /config/faults
+func (h *ConfigHandler) UpdateFaults(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	req := new(domain.FaultConfig)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	gen.UpdateFaults(*req)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"faults":  req,
+		"message": "fault injection updated successfully",
+	})
+}
+
+// GET /config/faults
+func (h *ConfigHandler) GetFaults(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	cfg, injected := gen.GetFaults()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"faults":   cfg,
+		"injected": injected,
+	})
+}
+
+type UpdateDevicesRequest struct {
+	Devices []domain.Device `json:"devices" validate:"required,min=1,dive"`
+}
+
+// PUT /config/devices
+func (h *ConfigHandler) UpdateDevices(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	req := new(UpdateDevicesRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := gen.UpdateDevices(req.Devices); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"devices": req.Devices,
+		"message": "devices updated successfully",
+	})
+}
+
+// GET /config/devices
+func (h *ConfigHandler) GetDevices(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"devices": gen.GetDevices(),
+	})
+}
+
+// PUT /config/correlation
+//
+// An empty object removes the correlation.
+func (h *ConfigHandler) UpdateCorrelation(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	req := new(domain.CorrelationConfig)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := gen.UpdateCorrelation(*req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"correlation": req,
+		"message":     "correlation updated successfully",
+	})
+}
+
+// GET /config/correlation
+func (h *ConfigHandler) GetCorrelation(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	cfg, nonFinite := gen.GetCorrelation()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"correlation": cfg,
+		"non_finite":  nonFinite,
+	})
+}
+
+// PUT /config/scenario
+//
+// Accepts a YAML or JSON scenario document and applies it immediately.
+func (h *ConfigHandler) UpdateScenario(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	scenario, err := domain.ParseScenario(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.validator.Struct(scenario); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":   "invalid scenario",
+			"details": validationDetails(err),
+		})
+	}
+
+	if err := gen.ApplyScenario(scenario); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":   "invalid scenario",
+			"details": []string{err.Error()},
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"scenario": scenario,
+		"message":  "scenario applied successfully",
+	})
+}
+
+// GET /config/scenario
+func (h *ConfigHandler) GetScenario(c echo.Context) error {
+	gen, err := h.generator(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, gen.GetScenario())
+}
+
+// validationDetails lists each failed field of a validator error.
+func validationDetails(err error) []string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []string{err.Error()}
+	}
+	details := make([]string, len(verrs))
+	for i, fe := range verrs {
+		details[i] = fe.Namespace() + ": failed on '" + fe.Tag() + "'"
+		if fe.Param() != "" {
+			details[i] += " (" + fe.Param() + ")"
+		}
+	}
+	return details
 }
\ No newline at end of file
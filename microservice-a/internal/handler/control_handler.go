@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/service"
+)
+
+// ControlHandler pauses, resumes and stops generators. Each action applies
+// to the generator selected with the "generator" query parameter, or to all
+// generators when it is omitted.
+type ControlHandler struct {
+	generators *service.GeneratorManager
+}
+
+func NewControlHandler(generators *service.GeneratorManager) *ControlHandler {
+	return &ControlHandler{generators: generators}
+}
+
+type controlResult struct {
+	service.ControlStatus
+	Error string `json:"error,omitempty"`
+}
+
+// POST /control/pause
+func (h *ControlHandler) Pause(c echo.Context) error {
+	return h.apply(c, h.generators.Pause)
+}
+
+// POST /control/resume
+func (h *ControlHandler) Resume(c echo.Context) error {
+	return h.apply(c, h.generators.Resume)
+}
+
+// POST /control/stop
+//
+// Closes the streams gracefully and reports microservice-b's WriteAck for
+// each of them.
+func (h *ControlHandler) Stop(c echo.Context) error {
+	return h.apply(c, h.generators.Stop)
+}
+
+func (h *ControlHandler) apply(c echo.Context, action func(id string) (service.ControlStatus, error)) error {
+	if id := c.QueryParam("generator"); id != "" {
+		status, err := action(id)
+		switch {
+		case errors.Is(err, service.ErrGeneratorNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		case err != nil:
+			return c.JSON(http.StatusConflict, controlResult{ControlStatus: status, Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, status)
+	}
+
+	// Stopping waits for each stream's ack, so act on all generators at once.
+	ids := h.generators.IDs()
+	results := make([]controlResult, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			status, err := action(id)
+			results[i].ControlStatus = status
+			results[i].ID = id
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	for _, r := range results {
+		if r.Error != "" {
+			code = http.StatusConflict
+		}
+	}
+	return c.JSON(code, map[string]interface{}{
+		"generators": results,
+	})
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/service"
+)
+
+type GeneratorsHandler struct {
+	generators *service.GeneratorManager
+	validator  *validator.Validate
+}
+
+func NewGeneratorsHandler(generators *service.GeneratorManager) *GeneratorsHandler {
+	return &GeneratorsHandler{
+		generators: generators,
+		validator:  validator.New(),
+	}
+}
+
+// GET /generators
+func (h *GeneratorsHandler) ListGenerators(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"generators": h.generators.List(),
+	})
+}
+
+// GET /generators/:id
+func (h *GeneratorsHandler) GetGenerator(c echo.Context) error {
+	info, err := h.generators.Info(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, info)
+}
+
+// POST /generators
+func (h *GeneratorsHandler) CreateGenerator(c echo.Context) error {
+	req := new(domain.GeneratorSpec)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	gen, err := h.generators.Add(*req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	info, _ := h.generators.Info(gen.ID())
+	return c.JSON(http.StatusCreated, info)
+}
+
+// DELETE /generators/:id
+func (h *GeneratorsHandler) DeleteGenerator(c echo.Context) error {
+	if err := h.generators.Remove(c.Param("id")); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "generator stopped",
+	})
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/service"
+)
+
+type ReplayHandler struct {
+	service   *service.ReplayService
+	validator *validator.Validate
+}
+
+func NewReplayHandler(service *service.ReplayService) *ReplayHandler {
+	return &ReplayHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// POST /replay
+func (h *ReplayHandler) StartReplay(c echo.Context) error {
+	req := new(domain.ReplayRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	job, err := h.service.Start(*req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GET /replay
+func (h *ReplayHandler) ListReplays(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"jobs": h.service.List(),
+	})
+}
+
+// GET /replay/:id
+func (h *ReplayHandler) GetReplay(c echo.Context) error {
+	job, ok := h.service.Get(c.Param("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "replay job not found"})
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+// DELETE /replay/:id
+func (h *ReplayHandler) CancelReplay(c echo.Context) error {
+	job, ok := h.service.Cancel(c.Param("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "replay job not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"job":     job,
+		"message": "replay job stopped",
+	})
+}
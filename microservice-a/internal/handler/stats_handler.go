@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/service"
+)
+
+type StatsHandler struct {
+	generators *service.GeneratorManager
+}
+
+func NewStatsHandler(generators *service.GeneratorManager) *StatsHandler {
+	return &StatsHandler{generators: generators}
+}
+
+// GET /stats
+//
+// Reports target and achieved rates of every generator, or of the one
+// selected with the "generator" query parameter.
+func (h *StatsHandler) GetStats(c echo.Context) error {
+	if id := c.QueryParam("generator"); id != "" {
+		gen, err := h.generators.Get(id)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, gen.Stats())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"generators": h.generators.Stats(),
+	})
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/grpc"
+)
+
+// BackfillService generates historical readings for a time window on a
+// dedicated stream, independent of the live generator.
+type BackfillService struct {
+	client     pb.IngestServiceClient
+	generators *GeneratorManager
+
+	mu       sync.Mutex
+	jobs     map[string]*backfillJob
+	finished []string // IDs of finished jobs, oldest first
+	nextID   int
+}
+
+// maxFinishedJobs is how many finished jobs a service keeps for GET; older
+// ones are forgotten.
+const maxFinishedJobs = 100
+
+type backfillJob struct {
+	job    domain.BackfillJob
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the job has finished
+}
+
+func NewBackfillService(conn *grpc.ClientConn, generators *GeneratorManager) *BackfillService {
+	return &BackfillService{
+		client:     pb.NewIngestServiceClient(conn),
+		generators: generators,
+		jobs:       make(map[string]*backfillJob),
+	}
+}
+
+// Start launches a backfill job using the selected generator's current value
+// model, devices, faults and batch size.
+func (s *BackfillService) Start(req domain.BackfillRequest) (domain.BackfillJob, error) {
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	gen, err := s.generators.Get(req.Generator)
+	if err != nil {
+		return domain.BackfillJob{}, err
+	}
+	sim, err := gen.forkSimulation(req.From)
+	if err != nil {
+		return domain.BackfillJob{}, err
+	}
+
+	total := int64(req.To.Sub(req.From) / interval)
+	if req.To.Sub(req.From)%interval != 0 {
+		total++
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	s.nextID++
+	j := &backfillJob{
+		job: domain.BackfillJob{
+			ID:            fmt.Sprintf("bf-%d", s.nextID),
+			Generator:     gen.ID(),
+			From:          req.From,
+			To:            req.To,
+			IntervalMs:    req.IntervalMs,
+			State:         domain.JobRunning,
+			TotalTicks:    total,
+			SimulatedTime: req.From,
+			StartedAt:     time.Now().UTC(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.jobs[j.job.ID] = j
+	snapshot := j.job
+	s.mu.Unlock()
+
+	go s.run(ctx, j, sim, interval, max(gen.config.Batch.Size, 1))
+	return snapshot, nil
+}
+
+func (s *BackfillService) run(ctx context.Context, j *backfillJob, sim *simulation, interval time.Duration, batchSize int) {
+	defer j.cancel()
+
+	stream, err := s.client.WriteBatch(ctx)
+	if err != nil {
+		s.finish(ctx, j, err)
+		return
+	}
+
+	// Readings are sent in full batches; the last one is flushed after
+	// the final tick.
+	var pending []*pb.Reading
+	for tick := int64(0); tick < j.job.TotalTicks; tick++ {
+		if ctx.Err() != nil {
+			s.finish(ctx, j, ctx.Err())
+			return
+		}
+
+		pending = append(pending, sim.tick(interval)...)
+		sent := 0
+		for len(pending) >= batchSize || (tick == j.job.TotalTicks-1 && len(pending) > 0) {
+			n := min(batchSize, len(pending))
+			if err := stream.Send(&pb.ReadingBatch{Readings: pending[:n]}); err != nil {
+				s.finish(ctx, j, err)
+				return
+			}
+			pending = pending[n:]
+			sent += n
+		}
+
+		s.mu.Lock()
+		j.job.CompletedTicks = tick + 1
+		j.job.ReadingsSent += uint64(sent)
+		j.job.Progress = float64(tick+1) / float64(j.job.TotalTicks)
+		j.job.SimulatedTime = sim.clock.Now()
+		s.mu.Unlock()
+	}
+
+	ack, err := stream.CloseAndRecv()
+	if err == nil {
+		s.mu.Lock()
+		j.job.ReadingsAcked = ack.GetCount()
+		j.job.ReadingsRejected = ack.GetRejected()
+		j.job.Rejections = describeRejections(ack.GetRejections())
+		s.mu.Unlock()
+	}
+	s.finish(ctx, j, err)
+}
+
+// describeRejections formats the rejections reported in a WriteAck.
+func describeRejections(rejections []*pb.Rejection) []string {
+	var out []string
+	for _, r := range rejections {
+		out = append(out, fmt.Sprintf("reading %d: %s", r.Index, r.Reason))
+	}
+	return out
+}
+
+func (s *BackfillService) finish(ctx context.Context, j *backfillJob, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	j.job.FinishedAt = &now
+	switch {
+	case ctx.Err() == context.Canceled:
+		j.job.State = domain.JobCancelled
+	case err != nil:
+		j.job.State = domain.JobFailed
+		j.job.Error = err.Error()
+	default:
+		j.job.State = domain.JobCompleted
+	}
+	close(j.done)
+
+	s.finished = append(s.finished, j.job.ID)
+	if len(s.finished) > maxFinishedJobs {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+func (s *BackfillService) Get(id string) (domain.BackfillJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return domain.BackfillJob{}, false
+	}
+	return j.job, true
+}
+
+func (s *BackfillService) List() []domain.BackfillJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]domain.BackfillJob, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].StartedAt.Before(jobs[b].StartedAt) })
+	return jobs
+}
+
+// Cancel stops a running job and waits for it to finish, so the returned job
+// is in its final state. It reports false if the job does not exist.
+func (s *BackfillService) Cancel(id string) (domain.BackfillJob, bool) {
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return domain.BackfillJob{}, false
+	}
+	j.cancel()
+	<-j.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return j.job, true
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/grpc"
+)
+
+// writeBatchClient accepts WriteBatch streams. While block is open, Send
+// blocks until the stream's context ends.
+type writeBatchClient struct {
+	pb.IngestServiceClient
+	block chan struct{}
+}
+
+func (c *writeBatchClient) WriteBatch(ctx context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[pb.ReadingBatch, pb.WriteAck], error) {
+	return &writeBatchStream{ctx: ctx, block: c.block}, nil
+}
+
+type writeBatchStream struct {
+	grpc.ClientStream
+	ctx   context.Context
+	block chan struct{}
+	count uint64
+}
+
+func (s *writeBatchStream) Send(batch *pb.ReadingBatch) error {
+	select {
+	case <-s.block:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	s.count += uint64(len(batch.Readings))
+	return nil
+}
+
+func (s *writeBatchStream) CloseAndRecv() (*pb.WriteAck, error) {
+	return &pb.WriteAck{Count: s.count}, nil
+}
+
+func newTestBackfillService(t *testing.T, client pb.IngestServiceClient) *BackfillService {
+	t.Helper()
+	m := newTestManager(t, "")
+	if _, err := m.Add(domain.GeneratorSpec{ID: "temperature", SensorType: "temperature"}); err != nil {
+		t.Fatal(err)
+	}
+	return &BackfillService{client: client, generators: m, jobs: make(map[string]*backfillJob)}
+}
+
+func backfillRequest() domain.BackfillRequest {
+	return domain.BackfillRequest{
+		From:       simStart,
+		To:         simStart.Add(time.Hour),
+		IntervalMs: 60000,
+	}
+}
+
+func TestBackfillCancelReturnsFinalState(t *testing.T) {
+	client := &writeBatchClient{block: make(chan struct{})}
+	s := newTestBackfillService(t, client)
+
+	job, err := s.Start(backfillRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, ok := s.Cancel(job.ID)
+	if !ok {
+		t.Fatal("job not found")
+	}
+	if cancelled.State != domain.JobCancelled || cancelled.FinishedAt == nil {
+		t.Errorf("Cancel returned state %q, finished at %v; want cancelled", cancelled.State, cancelled.FinishedAt)
+	}
+	if _, ok := s.Cancel("bf-missing"); ok {
+		t.Error("Cancel found a job that does not exist")
+	}
+}
+
+func TestBackfillKeepsLimitedFinishedJobs(t *testing.T) {
+	client := &writeBatchClient{block: make(chan struct{})}
+	close(client.block)
+	s := newTestBackfillService(t, client)
+
+	const jobs = maxFinishedJobs + 5
+	for i := 0; i < jobs; i++ {
+		job, err := s.Start(backfillRequest())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Cancel waits for the job, which has finished or stops now.
+		s.Cancel(job.ID)
+	}
+	if got := len(s.List()); got != maxFinishedJobs {
+		t.Errorf("%d jobs kept, want %d", got, maxFinishedJobs)
+	}
+	if _, ok := s.Get("bf-1"); ok {
+		t.Error("the oldest finished job was kept")
+	}
+	if _, ok := s.Get(fmt.Sprintf("bf-%d", jobs)); !ok {
+		t.Error("the newest finished job was forgotten")
+	}
+}
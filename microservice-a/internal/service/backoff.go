@@ -0,0 +1,35 @@
+package service
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff produces jittered exponential delays: each attempt doubles the
+// ceiling up to max, and the delay is drawn uniformly from the upper half
+// of it so that generators reconnecting together spread out.
+type backoff struct {
+	base    time.Duration
+	max     time.Duration
+	attempt int
+}
+
+func newBackoff(base, max time.Duration) *backoff {
+	return &backoff{base: base, max: max}
+}
+
+func (b *backoff) next() time.Duration {
+	ceiling := b.max
+	if b.attempt < 32 {
+		if d := b.base << b.attempt; d > 0 && d < b.max {
+			ceiling = d
+		}
+	}
+	b.attempt++
+	half := ceiling / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (b *backoff) reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffBounds(t *testing.T) {
+	const base, max = 100 * time.Millisecond, 5 * time.Second
+	b := newBackoff(base, max)
+	for attempt := 0; attempt < 100; attempt++ {
+		ceiling := max
+		if attempt < 6 { // 100ms << 6 exceeds 5s
+			ceiling = base << attempt
+		}
+		for i := 0; i < 20; i++ {
+			b.attempt = attempt
+			if d := b.next(); d < ceiling/2 || d > ceiling {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+}
+
+func TestBackoffJitters(t *testing.T) {
+	b := newBackoff(time.Second, time.Minute)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		b.reset()
+		seen[b.next()] = true
+	}
+	if len(seen) < 2 {
+		t.Error("20 first delays were all equal")
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := newBackoff(100*time.Millisecond, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.next()
+	}
+	b.reset()
+	if d := b.next(); d > 100*time.Millisecond {
+		t.Errorf("first delay after reset %v, want at most the base", d)
+	}
+}
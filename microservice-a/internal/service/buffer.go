@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// diskBuffer is a bounded on-disk FIFO of readings. Records are appended to
+// numbered segment files as a 4-byte big-endian length followed by the
+// protobuf encoding; fully drained segments are deleted. The read position
+// is persisted in a cursor file, so buffered readings survive restarts and
+// are drained at least once.
+//
+// Writes go straight to the file without user-space buffering, so they
+// survive a process crash; segments are fsynced when they are sealed.
+type diskBuffer struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+	policy       domain.OverflowPolicy
+
+	mu       sync.Mutex
+	segments []*bufferSegment // oldest first; the last one is written to
+	writer   *os.File
+	readOff  int64 // offset into segments[0]
+	size     int64 // unread bytes
+	records  int64 // unread records
+	dropped  uint64
+}
+
+type bufferSegment struct {
+	id      int64
+	size    int64
+	records int64
+}
+
+// bufferRecord is a reading returned by peekAfter with the position after it.
+type bufferRecord struct {
+	reading *pb.Reading
+	segment int64
+	end     int64
+}
+
+const (
+	bufferSegmentExt     = ".seg"
+	bufferCursorFile     = "cursor"
+	minBufferSegmentSize = 64 << 10
+	maxBufferRecordSize  = 1 << 20
+)
+
+// openDiskBuffer opens or creates the buffer in dir, recovering any
+// readings left from a previous run.
+func openDiskBuffer(dir string, maxBytes int64, policy domain.OverflowPolicy) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if policy == "" {
+		policy = domain.OverflowDropOldest
+	}
+	b := &diskBuffer{
+		dir:          dir,
+		maxBytes:     maxBytes,
+		segmentBytes: maxBytes / 16,
+		policy:       policy,
+	}
+	if b.segmentBytes < minBufferSegmentSize {
+		b.segmentBytes = minBufferSegmentSize
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, bufferSegmentExt) {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, bufferSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, &bufferSegment{id: id})
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i].id < b.segments[j].id })
+
+	cursorSeg, cursorOff := b.readCursor()
+	for len(b.segments) > 0 && b.segments[0].id < cursorSeg {
+		os.Remove(b.segmentPath(b.segments[0].id))
+		b.segments = b.segments[1:]
+	}
+	if len(b.segments) > 0 && b.segments[0].id == cursorSeg {
+		b.readOff = cursorOff
+	}
+
+	for i, seg := range b.segments {
+		from := int64(0)
+		if i == 0 {
+			from = b.readOff
+		}
+		if err := b.scanSegment(seg, from); err != nil {
+			return nil, err
+		}
+		if i == 0 && b.readOff > seg.size {
+			b.readOff = seg.size
+		}
+		b.size += seg.size
+		b.records += seg.records
+		if i == 0 {
+			b.size -= b.readOff
+		}
+	}
+
+	if len(b.segments) == 0 {
+		b.segments = append(b.segments, &bufferSegment{id: 1})
+	}
+	if err := b.openWriter(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *diskBuffer) segmentPath(id int64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", id, bufferSegmentExt))
+}
+
+func (b *diskBuffer) readCursor() (int64, int64) {
+	data, err := os.ReadFile(filepath.Join(b.dir, bufferCursorFile))
+	if err != nil {
+		return 0, 0
+	}
+	var seg, off int64
+	if _, err := fmt.Sscanf(string(data), "%d %d", &seg, &off); err != nil {
+		return 0, 0
+	}
+	return seg, off
+}
+
+func (b *diskBuffer) writeCursor() error {
+	path := filepath.Join(b.dir, bufferCursorFile)
+	tmp := path + ".tmp"
+	data := fmt.Sprintf("%d %d\n", b.segments[0].id, b.readOff)
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// scanSegment counts the records of seg after offset from and truncates a
+// torn record left by a crash.
+func (b *diskBuffer) scanSegment(seg *bufferSegment, from int64) error {
+	f, err := os.OpenFile(b.segmentPath(seg.id), os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var off int64
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			break
+		}
+		n := int64(binary.BigEndian.Uint32(header[:]))
+		if _, err := r.Discard(int(n)); err != nil {
+			break
+		}
+		off += 4 + n
+		if off > from {
+			seg.records++
+		}
+	}
+	seg.size = off
+	return f.Truncate(off)
+}
+
+func (b *diskBuffer) openWriter() error {
+	seg := b.segments[len(b.segments)-1]
+	f, err := os.OpenFile(b.segmentPath(seg.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	b.writer = f
+	return nil
+}
+
+// roll seals the write segment and starts a new one.
+func (b *diskBuffer) roll() error {
+	if err := b.writer.Sync(); err != nil {
+		return err
+	}
+	if err := b.writer.Close(); err != nil {
+		return err
+	}
+	last := b.segments[len(b.segments)-1]
+	b.segments = append(b.segments, &bufferSegment{id: last.id + 1})
+	return b.openWriter()
+}
+
+// append buffers msgs, applying the overflow policy when the buffer is full.
+func (b *diskBuffer) append(msgs []*pb.Reading) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, msg := range msgs {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		recSize := int64(4 + len(data))
+
+		if b.maxBytes > 0 && b.size+recSize > b.maxBytes {
+			if b.policy == domain.OverflowDropNewest {
+				b.dropped++
+				continue
+			}
+			if err := b.dropOldest(recSize); err != nil {
+				return err
+			}
+		}
+
+		seg := b.segments[len(b.segments)-1]
+		if seg.size > 0 && seg.size+recSize > b.segmentBytes {
+			if err := b.roll(); err != nil {
+				return err
+			}
+			seg = b.segments[len(b.segments)-1]
+		}
+
+		rec := make([]byte, recSize)
+		binary.BigEndian.PutUint32(rec, uint32(len(data)))
+		copy(rec[4:], data)
+		if _, err := b.writer.Write(rec); err != nil {
+			return err
+		}
+		seg.size += recSize
+		seg.records++
+		b.size += recSize
+		b.records++
+	}
+	return nil
+}
+
+// dropOldest discards whole segments from the head until need more bytes
+// fit. The write segment is sealed first if it is the only one left.
+func (b *diskBuffer) dropOldest(need int64) error {
+	for b.size > 0 && b.size+need > b.maxBytes {
+		if len(b.segments) == 1 {
+			if err := b.roll(); err != nil {
+				return err
+			}
+		}
+		head := b.segments[0]
+		b.size -= head.size - b.readOff
+		b.records -= head.records
+		b.dropped += uint64(head.records)
+		os.Remove(b.segmentPath(head.id))
+		b.segments = b.segments[1:]
+		b.readOff = 0
+	}
+	return b.writeCursor()
+}
+
+// peekAfter returns up to max readings following after without consuming
+// them, starting from the head when after is nil or has been dropped.
+// Records are consumed by commit once they have been acknowledged.
+func (b *diskBuffer) peekAfter(after *bufferRecord, max int) ([]bufferRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var from, fromOff int64
+	if len(b.segments) > 0 {
+		from, fromOff = b.segments[0].id, b.readOff
+	}
+	if after != nil && (after.segment > from || after.segment == from && after.end > fromOff) {
+		from, fromOff = after.segment, after.end
+	}
+
+	var out []bufferRecord
+	for _, seg := range b.segments {
+		if len(out) >= max {
+			break
+		}
+		if seg.id < from {
+			continue
+		}
+		off := int64(0)
+		if seg.id == from {
+			off = fromOff
+		}
+		if off >= seg.size {
+			continue
+		}
+		f, err := os.Open(b.segmentPath(seg.id))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Seek(off, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+		r := bufio.NewReader(io.LimitReader(f, seg.size-off))
+		var header [4]byte
+		for len(out) < max && off < seg.size {
+			if _, err := io.ReadFull(r, header[:]); err != nil {
+				f.Close()
+				return nil, err
+			}
+			n := binary.BigEndian.Uint32(header[:])
+			if n > maxBufferRecordSize {
+				f.Close()
+				return nil, fmt.Errorf("buffer segment %d: corrupt record at %d", seg.id, off)
+			}
+			data := make([]byte, n)
+			if _, err := io.ReadFull(r, data); err != nil {
+				f.Close()
+				return nil, err
+			}
+			msg := new(pb.Reading)
+			if err := proto.Unmarshal(data, msg); err != nil {
+				f.Close()
+				return nil, err
+			}
+			off += 4 + int64(n)
+			out = append(out, bufferRecord{reading: msg, segment: seg.id, end: off})
+		}
+		f.Close()
+	}
+	return out, nil
+}
+
+// commit consumes every record up to and including rec.
+func (b *diskBuffer) commit(rec bufferRecord) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.segments) > 0 && b.segments[0].id < rec.segment {
+		head := b.segments[0]
+		b.size -= head.size - b.readOff
+		b.records -= head.records
+		os.Remove(b.segmentPath(head.id))
+		b.segments = b.segments[1:]
+		b.readOff = 0
+	}
+	if len(b.segments) == 0 || b.segments[0].id > rec.segment {
+		return nil // dropped on overflow while being drained
+	}
+
+	head := b.segments[0]
+	consumed, err := b.countRecords(head, b.readOff, rec.end)
+	if err != nil {
+		return err
+	}
+	b.size -= rec.end - b.readOff
+	b.records -= consumed
+	head.records -= consumed
+	b.readOff = rec.end
+
+	// Delete the head once drained, unless it is still being written to.
+	if b.readOff == head.size && len(b.segments) > 1 {
+		os.Remove(b.segmentPath(head.id))
+		b.segments = b.segments[1:]
+		b.readOff = 0
+	}
+	return b.writeCursor()
+}
+
+// countRecords counts the records of seg in [from, to).
+func (b *diskBuffer) countRecords(seg *bufferSegment, from, to int64) (int64, error) {
+	f, err := os.Open(b.segmentPath(seg.id))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var n int64
+	var header [4]byte
+	for off := from; off < to; n++ {
+		if _, err := f.ReadAt(header[:], off); err != nil {
+			return 0, err
+		}
+		off += 4 + int64(binary.BigEndian.Uint32(header[:]))
+	}
+	return n, nil
+}
+
+// len returns the number of buffered readings and their size in bytes.
+func (b *diskBuffer) len() (int64, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.records, b.size
+}
+
+func (b *diskBuffer) droppedCount() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+func (b *diskBuffer) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.writer.Sync(); err != nil {
+		b.writer.Close()
+		return err
+	}
+	return b.writer.Close()
+}
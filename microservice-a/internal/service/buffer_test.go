@@ -0,0 +1,122 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+)
+
+func bufferReadings(from, n int) []*pb.Reading {
+	msgs := make([]*pb.Reading, n)
+	for i := range msgs {
+		msgs[i] = &pb.Reading{Id1: "A", Id2: int32(from + i), SensorType: "temperature", Value: 21.5, Timestamp: "2024-01-01T00:00:00Z"}
+	}
+	return msgs
+}
+
+// crash abandons b the way a killed process would: nothing is synced and
+// no cursor is written beyond what commit already persisted.
+func crash(b *diskBuffer) {
+	b.writer.Close()
+}
+
+// drain returns the id2 of every buffered reading without consuming them.
+func drain(t *testing.T, b *diskBuffer) []int32 {
+	t.Helper()
+	recs, err := b.peekAfter(nil, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int32, len(recs))
+	for i, rec := range recs {
+		ids[i] = rec.reading.Id2
+	}
+	return ids
+}
+
+func TestDiskBufferRecoversAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	b, err := openDiskBuffer(dir, 0, domain.OverflowDropOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.append(bufferReadings(0, 5)); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := b.peekAfter(nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.commit(recs[1]); err != nil {
+		t.Fatal(err)
+	}
+	crash(b)
+
+	// A record torn by the crash: its header promises more than was written.
+	f, err := os.OpenFile(b.segmentPath(1), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 100, 1, 2, 3})
+	f.Close()
+
+	b, err = openDiskBuffer(dir, 0, domain.OverflowDropOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	if n, _ := b.len(); n != 3 {
+		t.Fatalf("recovered %d readings, want 3", n)
+	}
+	if err := b.append(bufferReadings(5, 1)); err != nil {
+		t.Fatal(err)
+	}
+	got := drain(t, b)
+	want := []int32{2, 3, 4, 5}
+	if len(got) != len(want) {
+		t.Fatalf("buffered %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("buffered %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDiskBufferRecoversAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	b, err := openDiskBuffer(dir, 0, domain.OverflowDropOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const total = 5000 // spans several minimum-size segments
+	if err := b.append(bufferReadings(0, total)); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.segments) < 2 {
+		t.Fatalf("wrote %d segments, want several", len(b.segments))
+	}
+	recs, err := b.peekAfter(nil, 3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.commit(recs[len(recs)-1]); err != nil {
+		t.Fatal(err)
+	}
+	crash(b)
+
+	b, err = openDiskBuffer(dir, 0, domain.OverflowDropOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	if n, _ := b.len(); n != total-3000 {
+		t.Fatalf("recovered %d readings, want %d", n, total-3000)
+	}
+	got := drain(t, b)
+	if len(got) != total-3000 || got[0] != 3000 || got[len(got)-1] != total-1 {
+		t.Fatalf("recovered %d readings from %d to %d, want 3000 to %d", len(got), got[0], got[len(got)-1], total-1)
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import "time"
+
+// clock supplies reading timestamps. Seeded runs use a logicalClock so that
+// timestamps are reproducible along with the values.
+type clock interface {
+	Now() time.Time
+	Advance(d time.Duration)
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now().UTC() }
+
+func (wallClock) Advance(time.Duration) {}
+
+// logicalClock starts at a fixed instant and only moves when advanced by the
+// generator, one frequency interval per reading.
+type logicalClock struct {
+	now time.Time
+}
+
+func (c *logicalClock) Now() time.Time { return c.now }
+
+func (c *logicalClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// ConnStatus describes the gRPC connection to microservice-b.
+type ConnStatus struct {
+	State       string    `json:"state"` // IDLE, CONNECTING, READY, TRANSIENT_FAILURE or SHUTDOWN
+	Since       time.Time `json:"since"`
+	Transitions uint64    `json:"transitions"`
+}
+
+// connMonitor follows the connectivity state of a client connection so
+// senders can reconnect as soon as it becomes ready instead of waiting out
+// their backoff.
+type connMonitor struct {
+	mu          sync.Mutex
+	state       connectivity.State
+	since       time.Time
+	transitions uint64
+	change      chan struct{} // closed on the next state change
+}
+
+func newConnMonitor(ctx context.Context, conn *grpc.ClientConn) *connMonitor {
+	m := &connMonitor{
+		state:  conn.GetState(),
+		since:  time.Now(),
+		change: make(chan struct{}),
+	}
+	conn.Connect()
+	go func() {
+		state := m.state
+		for conn.WaitForStateChange(ctx, state) {
+			state = conn.GetState()
+			m.set(state)
+		}
+	}()
+	return m
+}
+
+func (m *connMonitor) set(state connectivity.State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log.Printf("microservice-b connection: %s -> %s", m.state, state)
+	m.state = state
+	m.since = time.Now()
+	m.transitions++
+	close(m.change)
+	m.change = make(chan struct{})
+}
+
+func (m *connMonitor) current() (connectivity.State, <-chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state, m.change
+}
+
+func (m *connMonitor) status() ConnStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return ConnStatus{State: m.state.String(), Since: m.since, Transitions: m.transitions}
+}
+
+// waitRetry waits for d, returning early when the connection becomes ready.
+// It reports false if ctx is done.
+func (m *connMonitor) waitRetry(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		state, changed := m.current()
+		if state == connectivity.Ready {
+			// Already ready: the failure was the stream's, so honour the delay.
+			changed = nil
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-changed:
+			if s, _ := m.current(); s == connectivity.Ready {
+				return true
+			}
+		}
+	}
+}
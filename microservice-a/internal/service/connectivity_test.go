@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func newTestMonitor(state connectivity.State) *connMonitor {
+	return &connMonitor{state: state, since: time.Now(), change: make(chan struct{})}
+}
+
+// startWait runs waitRetry in the background and returns its result channel.
+func startWait(ctx context.Context, m *connMonitor, d time.Duration) <-chan bool {
+	result := make(chan bool, 1)
+	go func() { result <- m.waitRetry(ctx, d) }()
+	return result
+}
+
+func TestWaitRetryReturnsWhenReady(t *testing.T) {
+	m := newTestMonitor(connectivity.TransientFailure)
+	result := startWait(context.Background(), m, time.Hour)
+
+	m.set(connectivity.Connecting)
+	select {
+	case <-result:
+		t.Fatal("waitRetry returned on CONNECTING")
+	case <-time.After(20 * time.Millisecond):
+	}
+	m.set(connectivity.Ready)
+	select {
+	case ok := <-result:
+		if !ok {
+			t.Error("waitRetry reported a cancelled context")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitRetry did not return when the connection became ready")
+	}
+	if s := m.status(); s.State != "READY" || s.Transitions != 2 {
+		t.Errorf("status = %+v, want READY after 2 transitions", s)
+	}
+}
+
+func TestWaitRetryHonoursDelayWhenAlreadyReady(t *testing.T) {
+	m := newTestMonitor(connectivity.Ready)
+	const delay = 50 * time.Millisecond
+	start := time.Now()
+	if !m.waitRetry(context.Background(), delay) {
+		t.Fatal("waitRetry reported a cancelled context")
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("waitRetry returned after %v, want the full %v", elapsed, delay)
+	}
+}
+
+func TestWaitRetryTimesOut(t *testing.T) {
+	m := newTestMonitor(connectivity.TransientFailure)
+	if !m.waitRetry(context.Background(), 10*time.Millisecond) {
+		t.Error("waitRetry reported a cancelled context")
+	}
+}
+
+func TestWaitRetryStopsWithContext(t *testing.T) {
+	m := newTestMonitor(connectivity.TransientFailure)
+	ctx, cancel := context.WithCancel(context.Background())
+	result := startWait(ctx, m, time.Hour)
+	cancel()
+	select {
+	case ok := <-result:
+		if ok {
+			t.Error("waitRetry reported true after its context was cancelled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitRetry ignored the cancelled context")
+	}
+}
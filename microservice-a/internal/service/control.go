@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// GeneratorState is where a generator is in its lifecycle:
+//
+//	running -> paused -> running
+//	running, paused -> stopping -> stopped -> running
+//
+// A paused generator keeps its stream open but emits nothing. Stopping
+// closes the stream gracefully and waits for microservice-b's WriteAck.
+type GeneratorState string
+
+const (
+	StateRunning  GeneratorState = "running"
+	StatePaused   GeneratorState = "paused"
+	StateStopping GeneratorState = "stopping"
+	StateStopped  GeneratorState = "stopped"
+)
+
+// ErrInvalidTransition is returned for control requests the current state
+// does not allow, such as pausing a stopped generator.
+var ErrInvalidTransition = fmt.Errorf("invalid state transition")
+
+// ControlStatus describes a generator's state and the acknowledgement of
+// its last gracefully closed stream.
+type ControlStatus struct {
+	ID      string         `json:"id"`
+	State   GeneratorState `json:"state"`
+	Since   time.Time      `json:"since"`
+	LastAck *StreamAck     `json:"last_ack,omitempty"`
+}
+
+// control holds a generator's state and lets the emission loop wait for
+// changes to it.
+type control struct {
+	mu      sync.Mutex
+	state   GeneratorState
+	since   time.Time
+	changed chan struct{} // closed on the next transition
+}
+
+func newControl() *control {
+	return &control{state: StateRunning, since: time.Now(), changed: make(chan struct{})}
+}
+
+// transition moves to state to if the current state is one of from. Moving
+// to the current state is a no-op.
+func (c *control) transition(to GeneratorState, from ...GeneratorState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == to {
+		return nil
+	}
+	for _, f := range from {
+		if c.state == f {
+			c.state = to
+			c.since = time.Now()
+			close(c.changed)
+			c.changed = make(chan struct{})
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: generator is %s", ErrInvalidTransition, c.state)
+}
+
+func (c *control) current() (GeneratorState, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state, c.changed
+}
+
+func (c *control) status() (GeneratorState, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state, c.since
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestControlTransitions(t *testing.T) {
+	// The transitions GeneratorService and GeneratorManager request.
+	ops := map[string]func(c *control) error{
+		"pause":   func(c *control) error { return c.transition(StatePaused, StateRunning) },
+		"resume":  func(c *control) error { return c.transition(StateRunning, StatePaused) },
+		"stop":    func(c *control) error { return c.transition(StateStopping, StateRunning, StatePaused) },
+		"stopped": func(c *control) error { return c.transition(StateStopped, StateStopping) },
+		"restart": func(c *control) error { return c.transition(StateRunning, StateStopped) },
+	}
+	for _, tt := range []struct {
+		from GeneratorState
+		op   string
+		want GeneratorState // empty if the transition is rejected
+	}{
+		{StateRunning, "pause", StatePaused},
+		{StateRunning, "resume", StateRunning},
+		{StateRunning, "stop", StateStopping},
+		{StateRunning, "stopped", ""},
+		{StateRunning, "restart", StateRunning},
+
+		{StatePaused, "pause", StatePaused},
+		{StatePaused, "resume", StateRunning},
+		{StatePaused, "stop", StateStopping},
+		{StatePaused, "stopped", ""},
+		{StatePaused, "restart", ""},
+
+		{StateStopping, "pause", ""},
+		{StateStopping, "resume", ""},
+		{StateStopping, "stop", StateStopping},
+		{StateStopping, "stopped", StateStopped},
+		{StateStopping, "restart", ""},
+
+		{StateStopped, "pause", ""},
+		{StateStopped, "resume", ""},
+		{StateStopped, "stop", ""},
+		{StateStopped, "stopped", StateStopped},
+		{StateStopped, "restart", StateRunning},
+	} {
+		c := newControl()
+		c.state = tt.from
+		_, changed := c.current()
+
+		err := ops[tt.op](c)
+		state, _ := c.status()
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidTransition) || state != tt.from {
+				t.Errorf("%s from %s: %v, now %s; want ErrInvalidTransition", tt.op, tt.from, err, state)
+			}
+			continue
+		}
+		if err != nil || state != tt.want {
+			t.Errorf("%s from %s: %v, now %s; want %s", tt.op, tt.from, err, state, tt.want)
+		}
+		select {
+		case <-changed:
+			if state == tt.from {
+				t.Errorf("%s from %s: no-op signalled a change", tt.op, tt.from)
+			}
+		default:
+			if state != tt.from {
+				t.Errorf("%s from %s: change to %s was not signalled", tt.op, tt.from, state)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+)
+
+// signalBus holds the latest value each generator produced per device, so
+// generators in the same process can derive correlated values from each
+// other. Values are sampled and held: a generator sees whatever its sources
+// last emitted, which keeps generators with different frequencies independent.
+// When several generators share a sensor type, the latest value per device
+// wins, whichever generator published it.
+type signalBus struct {
+	mu     sync.RWMutex
+	latest map[string]map[domain.Device]busValue // sensor type -> device -> value
+}
+
+// busValue is a published value and the ID of the generator that published it.
+type busValue struct {
+	v         float64
+	generator string
+}
+
+func newSignalBus() *signalBus {
+	return &signalBus{latest: make(map[string]map[domain.Device]busValue)}
+}
+
+func (b *signalBus) publish(generator, sensorType string, device domain.Device, v float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	values, ok := b.latest[sensorType]
+	if !ok {
+		values = make(map[domain.Device]busValue)
+		b.latest[sensorType] = values
+	}
+	values[device] = busValue{v: v, generator: generator}
+}
+
+// forget drops the values of a sensor type that a generator published last,
+// e.g. when it stops. Values of other generators stay.
+func (b *signalBus) forget(generator, sensorType string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	values := b.latest[sensorType]
+	for d, bv := range values {
+		if bv.generator == generator {
+			delete(values, d)
+		}
+	}
+	if len(values) == 0 {
+		delete(b.latest, sensorType)
+	}
+}
+
+func (b *signalBus) device(sensorType string, device domain.Device) (float64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	bv, ok := b.latest[sensorType][device]
+	return bv.v, ok
+}
+
+// site returns the mean of a sensor type over all devices sharing id1.
+func (b *signalBus) site(sensorType, id1 string) (float64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var sum float64
+	var n int
+	for d, bv := range b.latest[sensorType] {
+		if d.ID1 == id1 {
+			sum += bv.v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// correlator applies a CorrelationConfig to a generator's model output.
+type correlator struct {
+	cfg     domain.CorrelationConfig
+	formula *formula
+	invalid uint64 // results that were not finite numbers
+}
+
+func newCorrelator(cfg domain.CorrelationConfig) (*correlator, error) {
+	c := &correlator{cfg: cfg}
+	for i, t := range cfg.Terms {
+		if t.Source == "" {
+			return nil, fmt.Errorf("terms[%d]: source is required", i)
+		}
+		switch t.Scope {
+		case "", domain.CorrelationDevice, domain.CorrelationSite:
+		default:
+			return nil, fmt.Errorf("terms[%d]: unknown scope %q", i, t.Scope)
+		}
+	}
+	if strings.TrimSpace(cfg.Formula) != "" {
+		f, err := compileFormula(cfg.Formula)
+		if err != nil {
+			return nil, err
+		}
+		c.formula = f
+	}
+	return c, nil
+}
+
+// apply derives the value of device from its model output v and the latest
+// values on bus. If a source has not produced a value yet, v is returned. So
+// is it when the result is not a finite number, e.g. the log of a negative
+// value or a division by zero, which is counted.
+func (c *correlator) apply(bus *signalBus, device domain.Device, v float64) float64 {
+	out := c.derive(bus, device, v)
+	if math.IsNaN(out) || math.IsInf(out, 0) {
+		c.invalid++
+		return v
+	}
+	return out
+}
+
+func (c *correlator) derive(bus *signalBus, device domain.Device, v float64) float64 {
+	if c.formula != nil {
+		out, ok := c.formula.Eval(func(name string) (float64, bool) {
+			if name == "value" {
+				return v, true
+			}
+			if source, ok := strings.CutPrefix(name, "site."); ok {
+				return bus.site(source, device.ID1)
+			}
+			return bus.device(name, device)
+		})
+		if !ok {
+			return v
+		}
+		return out
+	}
+
+	out := v
+	for _, t := range c.cfg.Terms {
+		var source float64
+		var ok bool
+		if t.Scope == domain.CorrelationSite {
+			source, ok = bus.site(t.Source, device.ID1)
+		} else {
+			source, ok = bus.device(t.Source, device)
+		}
+		if !ok {
+			return v
+		}
+		out += t.Coefficient * (source - t.Reference)
+	}
+	return out
+}
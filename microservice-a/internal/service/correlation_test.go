@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+)
+
+func TestCorrelatorFallsBackOnNonFiniteResults(t *testing.T) {
+	bus := newSignalBus()
+	device := domain.Device{ID1: "A", ID2: 1}
+	bus.publish("gen", "humidity", device, 0)
+
+	for _, src := range []string{"log(humidity - 1)", "sqrt(humidity - 1)", "value / humidity", "exp(1000)"} {
+		c, err := newCorrelator(domain.CorrelationConfig{Formula: src})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.apply(bus, device, 21.5); got != 21.5 {
+			t.Errorf("%q = %v, want the model value 21.5", src, got)
+		}
+		if _, invalid := (&simulation{corr: c}).correlation(); invalid != 1 {
+			t.Errorf("%q counted %d non-finite results, want 1", src, invalid)
+		}
+	}
+}
+
+func TestCorrelatorTerms(t *testing.T) {
+	bus := newSignalBus()
+	bus.publish("gen", "temperature", domain.Device{ID1: "A", ID2: 1}, 25)
+	bus.publish("gen", "temperature", domain.Device{ID1: "A", ID2: 2}, 21)
+	c, err := newCorrelator(domain.CorrelationConfig{Terms: []domain.CorrelationTerm{
+		{Source: "temperature", Coefficient: -1.5, Reference: 21},
+		{Source: "temperature", Scope: domain.CorrelationSite, Coefficient: 1, Reference: 20},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50 - 1.5 * (25 - 21) + (23 - 20)
+	if got := c.apply(bus, domain.Device{ID1: "A", ID2: 1}, 50); got != 47 {
+		t.Errorf("apply = %v, want 47", got)
+	}
+	// No temperature on device A:3 yet.
+	if got := c.apply(bus, domain.Device{ID1: "A", ID2: 3}, 50); got != 50 {
+		t.Errorf("apply without a source value = %v, want 50", got)
+	}
+}
+
+func TestSignalBusForgetsOnlyOneGenerator(t *testing.T) {
+	bus := newSignalBus()
+	a1, a2 := domain.Device{ID1: "A", ID2: 1}, domain.Device{ID1: "A", ID2: 2}
+	bus.publish("north", "temperature", a1, 20)
+	bus.publish("south", "temperature", a2, 30)
+	bus.publish("north", "humidity", a1, 50)
+
+	bus.forget("north", "temperature")
+	if _, ok := bus.device("temperature", a1); ok {
+		t.Error("north's temperature survived forget")
+	}
+	if v, ok := bus.device("temperature", a2); !ok || v != 30 {
+		t.Errorf("south's temperature = %v, %v after forgetting north, want 30", v, ok)
+	}
+	if v, ok := bus.site("temperature", "A"); !ok || v != 30 {
+		t.Errorf("site mean = %v, %v, want 30", v, ok)
+	}
+	if _, ok := bus.device("humidity", a1); !ok {
+		t.Error("forgetting north's temperature dropped its humidity")
+	}
+
+	// The latest publisher of a device owns its value.
+	bus.publish("north", "temperature", a2, 25)
+	bus.forget("south", "temperature")
+	if v, ok := bus.device("temperature", a2); !ok || v != 25 {
+		t.Errorf("temperature = %v, %v after forgetting south, want north's 25", v, ok)
+	}
+}
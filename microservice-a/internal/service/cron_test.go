@@ -0,0 +1,112 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cronSet(set map[int]bool) []int {
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values
+}
+
+func TestParseCronField(t *testing.T) {
+	for _, tt := range []struct {
+		field    string
+		min, max int
+		want     []int
+	}{
+		{"5", 0, 59, []int{5}},
+		{"1,3,5", 0, 59, []int{1, 3, 5}},
+		{"10-13", 0, 59, []int{10, 11, 12, 13}},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}},
+		{"0-10/5", 0, 59, []int{0, 5, 10}},
+		{"50/3", 0, 59, []int{50, 53, 56, 59}},
+		{"*", 1, 4, []int{1, 2, 3, 4}},
+		{"1-2,4", 1, 4, []int{1, 2, 4}},
+		{"jan-mar", 1, 12, []int{1, 2, 3}},
+		{"MON,fri", 0, 7, []int{1, 5}},
+	} {
+		set, err := parseCronField(tt.field, tt.min, tt.max, map[string]int{
+			"jan": 1, "mar": 3, "mon": 1, "fri": 5,
+		})
+		if err != nil {
+			t.Errorf("parseCronField(%q): %v", tt.field, err)
+			continue
+		}
+		if got := cronSet(set); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCronField(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, tt := range []struct{ expr, want string }{
+		{"* * * *", "expected 5 fields"},
+		{"* * * * * *", "expected 5 fields"},
+		{"60 * * * *", "minute: invalid value"},
+		{"* 24 * * *", "hour: invalid value"},
+		{"* * 0 * *", "day of month: invalid value"},
+		{"* * * 13 * ", "month: invalid value"},
+		{"* * * foo *", "month: invalid value"},
+		{"* * * * 8", "day of week: invalid value"},
+		{"*/0 * * * *", "invalid step"},
+		{"*/x * * * *", "invalid step"},
+		{"30-10 * * * *", "invalid range"},
+		{"1,,2 * * * *", "invalid value"},
+	} {
+		_, err := parseCron(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseCron(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2024-01-15 is a Monday.
+	at := func(s string) time.Time {
+		t.Helper()
+		tm, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	for _, tt := range []struct {
+		expr string
+		at   string
+		want bool
+	}{
+		{"* * * * *", "2024-01-15 10:30", true},
+		{"30 10 * * *", "2024-01-15 10:30", true},
+		{"30 10 * * *", "2024-01-15 10:31", false},
+		{"*/15 9-17 * * mon-fri", "2024-01-15 17:45", true},
+		{"*/15 9-17 * * mon-fri", "2024-01-13 12:00", false},
+		{"0 0 * * 7", "2024-01-14 00:00", true}, // 7 is Sunday
+		{"0 0 * * sun", "2024-01-14 00:00", true},
+		{"0 0 1 jan *", "2024-01-01 00:00", true},
+		{"0 0 1 jan *", "2024-02-01 00:00", false},
+		// A restricted day of month and day of week match if either does.
+		{"0 0 1 * mon", "2024-01-15 00:00", true},
+		{"0 0 1 * mon", "2024-02-01 00:00", true},
+		{"0 0 1 * mon", "2024-01-16 00:00", false},
+		// With the other day field unrestricted, only the restricted one counts.
+		{"0 0 1 * *", "2024-01-15 00:00", false},
+		{"0 0 * * mon", "2024-01-01 00:00", true},
+	} {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := c.matches(at(tt.at)); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.expr, tt.at, got, tt.want)
+		}
+	}
+}
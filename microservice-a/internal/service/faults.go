@@ -0,0 +1,105 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/protobuf/proto"
+)
+
+var malformedID1s = []string{"", "a", "AB1", "??", "TOOLONGIDENTIFIER"}
+
+// faultInjector turns one clean reading into zero or more faulty ones. Each
+// device has its own injector; count is shared so totals cover the fleet.
+// It is not safe for concurrent use.
+type faultInjector struct {
+	cfg    domain.FaultConfig
+	rng    *rand.Rand
+	count  map[domain.FaultKind]uint64
+	newKey func() string // reading_id for injected duplicates
+
+	hasLast   bool
+	lastValue float64
+	stuckLeft int
+	held      *pb.Reading
+}
+
+func newFaultInjector(cfg domain.FaultConfig, rng *rand.Rand, count map[domain.FaultKind]uint64, newKey func() string) *faultInjector {
+	return &faultInjector{cfg: cfg, rng: rng, count: count, newKey: newKey}
+}
+
+func (f *faultInjector) hit(p float64, kind domain.FaultKind) bool {
+	if p <= 0 || f.rng.Float64() >= p {
+		return false
+	}
+	f.count[kind]++
+	return true
+}
+
+// Apply returns the readings to send in place of r, in order.
+func (f *faultInjector) Apply(r *pb.Reading) []*pb.Reading {
+	if f.stuckLeft > 0 {
+		f.stuckLeft--
+		r.Value = f.lastValue
+	} else if f.hasLast && f.hit(f.cfg.StuckProbability, domain.FaultStuck) {
+		f.stuckLeft = f.cfg.StuckReadings
+		r.Value = f.lastValue
+	}
+	f.lastValue = r.Value
+	f.hasLast = true
+
+	if f.hit(f.cfg.SpikeProbability, domain.FaultSpike) {
+		if f.rng.Intn(2) == 0 {
+			r.Value += f.cfg.SpikeMagnitude
+		} else {
+			r.Value -= f.cfg.SpikeMagnitude
+		}
+	}
+	if f.hit(f.cfg.NaNProbability, domain.FaultNaN) {
+		r.Value = math.NaN()
+	} else if f.hit(f.cfg.InfProbability, domain.FaultInf) {
+		r.Value = math.Inf(1 - 2*f.rng.Intn(2))
+	}
+
+	if f.hit(f.cfg.SkewProbability, domain.FaultSkew) {
+		if ts, err := time.Parse(time.RFC3339Nano, r.Timestamp); err == nil {
+			skew := (f.rng.Float64()*2 - 1) * f.cfg.MaxSkewSeconds
+			r.Timestamp = ts.Add(time.Duration(skew * float64(time.Second))).Format(time.RFC3339Nano)
+		}
+	}
+
+	if f.hit(f.cfg.MalformedIDProbability, domain.FaultMalformedID) {
+		if f.rng.Intn(2) == 0 {
+			r.Id1 = malformedID1s[f.rng.Intn(len(malformedID1s))]
+		} else {
+			r.Id2 = int32(1000 + f.rng.Intn(1000))
+			if f.rng.Intn(2) == 0 {
+				r.Id2 = -r.Id2
+			}
+		}
+	}
+
+	var out []*pb.Reading
+	if !f.hit(f.cfg.DropProbability, domain.FaultDrop) {
+		out = append(out, r)
+		if f.hit(f.cfg.DuplicateProbability, domain.FaultDuplicate) {
+			dup := proto.Clone(r).(*pb.Reading)
+			if !f.cfg.DuplicateReuseKey {
+				dup.ReadingId = f.newKey()
+			}
+			out = append(out, dup)
+		}
+	}
+
+	if f.held != nil {
+		out = append(out, f.held)
+		f.held = nil
+	} else if len(out) > 0 && f.hit(f.cfg.OutOfOrderProbability, domain.FaultOutOfOrder) {
+		f.held = out[0]
+		out = out[1:]
+	}
+	return out
+}
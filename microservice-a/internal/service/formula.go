@@ -0,0 +1,295 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// formula is a compiled correlation expression. Variables are resolved at
+// evaluation time; an unresolved variable makes Eval report false.
+type formula struct {
+	root exprNode
+}
+
+type exprNode interface {
+	eval(lookup func(string) (float64, bool)) (float64, bool)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(func(string) (float64, bool)) (float64, bool) {
+	return float64(n), true
+}
+
+type varNode string
+
+func (n varNode) eval(lookup func(string) (float64, bool)) (float64, bool) {
+	return lookup(string(n))
+}
+
+type unaryNode struct {
+	op      byte
+	operand exprNode
+}
+
+func (n unaryNode) eval(lookup func(string) (float64, bool)) (float64, bool) {
+	v, ok := n.operand.eval(lookup)
+	if n.op == '-' {
+		v = -v
+	}
+	return v, ok
+}
+
+type binaryNode struct {
+	op          byte
+	left, right exprNode
+}
+
+func (n binaryNode) eval(lookup func(string) (float64, bool)) (float64, bool) {
+	l, ok := n.left.eval(lookup)
+	if !ok {
+		return 0, false
+	}
+	r, ok := n.right.eval(lookup)
+	if !ok {
+		return 0, false
+	}
+	switch n.op {
+	case '+':
+		return l + r, true
+	case '-':
+		return l - r, true
+	case '*':
+		return l * r, true
+	case '/':
+		return l / r, true
+	default: // '^'
+		return math.Pow(l, r), true
+	}
+}
+
+type callNode struct {
+	fn   func(args []float64) float64
+	args []exprNode
+}
+
+func (n callNode) eval(lookup func(string) (float64, bool)) (float64, bool) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, ok := a.eval(lookup)
+		if !ok {
+			return 0, false
+		}
+		args[i] = v
+	}
+	return n.fn(args), true
+}
+
+type formulaFunc struct {
+	arity int
+	fn    func(args []float64) float64
+}
+
+var formulaFuncs = map[string]formulaFunc{
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"log":   {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"clamp": {3, func(a []float64) float64 { return math.Max(a[1], math.Min(a[2], a[0])) }},
+}
+
+// compileFormula parses src into a formula.
+func compileFormula(src string) (*formula, error) {
+	p := &formulaParser{src: src}
+	p.next()
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("formula: unexpected %q at %d", p.tok, p.tokPos)
+	}
+	return &formula{root: root}, nil
+}
+
+// Eval evaluates the formula, reporting false if a variable is unresolved.
+func (f *formula) Eval(lookup func(string) (float64, bool)) (float64, bool) {
+	return f.root.eval(lookup)
+}
+
+type formulaParser struct {
+	src    string
+	pos    int
+	tok    string
+	tokPos int
+}
+
+func isIdentRune(r rune, first bool) bool {
+	if r == '_' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && (unicode.IsDigit(r) || r == '.')
+}
+
+// next advances to the next token; tok is "" at the end of input.
+func (p *formulaParser) next() {
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		p.pos += size
+	}
+	p.tokPos = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	c := rune(p.src[p.pos])
+	start := p.pos
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.' ||
+			p.src[p.pos] == 'e' || p.src[p.pos] == 'E' ||
+			((p.src[p.pos] == '-' || p.src[p.pos] == '+') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E'))) {
+			p.pos++
+		}
+	case isIdentRune(c, true):
+		for p.pos < len(p.src) && isIdentRune(rune(p.src[p.pos]), false) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func (p *formulaParser) parseExpr() (exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok[0]
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseTerm() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "*" || p.tok == "/" {
+		op := p.tok[0]
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseUnary() (exprNode, error) {
+	if p.tok == "-" || p.tok == "+" {
+		op := p.tok[0]
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *formulaParser) parsePower() (exprNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok == "^" {
+		p.next()
+		exp, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: '^', left: base, right: exp}, nil
+	}
+	return base, nil
+}
+
+func (p *formulaParser) parsePrimary() (exprNode, error) {
+	tok, pos := p.tok, p.tokPos
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("formula: unexpected end of input")
+	case tok == "(":
+		p.next()
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("formula: expected ) at %d", p.tokPos)
+		}
+		p.next()
+		return inner, nil
+	case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("formula: invalid number %q at %d", tok, pos)
+		}
+		p.next()
+		return numberNode(v), nil
+	case isIdentRune(rune(tok[0]), true):
+		p.next()
+		if p.tok != "(" {
+			return varNode(tok), nil
+		}
+		fn, ok := formulaFuncs[strings.ToLower(tok)]
+		if !ok {
+			return nil, fmt.Errorf("formula: unknown function %q at %d", tok, pos)
+		}
+		p.next()
+		var args []exprNode
+		for p.tok != ")" {
+			if len(args) > 0 {
+				if p.tok != "," {
+					return nil, fmt.Errorf("formula: expected , at %d", p.tokPos)
+				}
+				p.next()
+			}
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		p.next()
+		if len(args) != fn.arity {
+			return nil, fmt.Errorf("formula: %s takes %d arguments, got %d", tok, fn.arity, len(args))
+		}
+		return callNode{fn: fn.fn, args: args}, nil
+	default:
+		return nil, fmt.Errorf("formula: unexpected %q at %d", tok, pos)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFormulaEval(t *testing.T) {
+	vars := map[string]float64{"value": 10, "temperature": 25, "site.co2": 400}
+	lookup := func(name string) (float64, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	for _, tt := range []struct {
+		src  string
+		want float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"24 / 4 / 2", 3},
+		{"2 ^ 3 ^ 2", 512},
+		{"-2 ^ 2", -4},
+		{"2 ^ -1", 0.5},
+		{"--3", 3},
+		{"+3", 3},
+		{"1.5e2 + 2.5E-1", 150.25},
+		{".5 * 4", 2},
+		{"value - 0.8 * (temperature - 21)", 6.8},
+		{"value + 0.01 * site.co2", 14},
+		{"clamp(value * 20, 0, 100)", 100},
+		{"max(value, temperature) - MIN(value, temperature)", 15},
+		{"abs(-3) + sqrt(16) + pow(2, 3)", 15},
+		{"exp(0) + log(1) + sin(0) + cos(0)", 2},
+		{"\tvalue\n*\r\n2 + 1", 21},
+	} {
+		f, err := compileFormula(tt.src)
+		if err != nil {
+			t.Errorf("compileFormula(%q): %v", tt.src, err)
+			continue
+		}
+		got, ok := f.Eval(lookup)
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q = %v, %v, want %v", tt.src, got, ok, tt.want)
+		}
+	}
+}
+
+func TestFormulaUnresolvedVariable(t *testing.T) {
+	f, err := compileFormula("value + humidity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.Eval(func(name string) (float64, bool) { return 1, name == "value" }); ok {
+		t.Error("formula with an unresolved variable evaluated")
+	}
+}
+
+func TestCompileFormulaErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src, err string
+	}{
+		{"", "unexpected end of input"},
+		{"1 +", "unexpected end of input"},
+		{"(1 + 2", "expected )"},
+		{"1 + 2)", `unexpected ")"`},
+		{"1 2", `unexpected "2"`},
+		{"1..2", "invalid number"},
+		{"foo(1)", `unknown function "foo"`},
+		{"min(1)", "min takes 2 arguments, got 1"},
+		{"abs(1, 2)", "abs takes 1 arguments, got 2"},
+		{"clamp(1 2 3)", "expected ,"},
+		{"1 $ 2", `unexpected "$"`},
+	} {
+		_, err := compileFormula(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("compileFormula(%q) = %v, want an error containing %q", tt.src, err, tt.err)
+		}
+	}
+}
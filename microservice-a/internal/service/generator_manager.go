@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	"google.golang.org/grpc"
+)
+
+// ErrGeneratorNotFound is returned for unknown generator IDs.
+var ErrGeneratorNotFound = fmt.Errorf("generator not found")
+
+// GeneratorInfo summarizes a running generator.
+type GeneratorInfo struct {
+	ID            string             `json:"id"`
+	SensorType    string             `json:"sensor_type"`
+	Unit          string             `json:"unit"`
+	FrequencyMs   int64              `json:"frequency_ms"`
+	IntervalUs    float64            `json:"interval_us"`
+	RatePerSecond float64            `json:"rate_per_second,omitempty"` // target, when pacing by rate
+	Devices       []domain.Device    `json:"devices"`
+	Seed          int64              `json:"seed"`
+	Deterministic bool               `json:"deterministic"`
+	Batch         domain.BatchConfig `json:"batch"`
+	Primary       bool               `json:"primary"`
+	State         GeneratorState     `json:"state"`
+}
+
+// GeneratorManager runs several generators in one process, each with its
+// own sensor type, frequency, device fleet and ingest stream.
+type GeneratorManager struct {
+	ctx      context.Context
+	conn     *grpc.ClientConn
+	defaults domain.GeneratorConfig
+	bus      *signalBus
+	monitor  *connMonitor
+
+	mu         sync.Mutex
+	generators map[string]*managedGenerator
+	primary    string
+}
+
+type managedGenerator struct {
+	service *GeneratorService
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewGeneratorManager creates a manager whose generators inherit defaults
+// and stop when ctx is cancelled.
+func NewGeneratorManager(ctx context.Context, conn *grpc.ClientConn, defaults domain.GeneratorConfig) *GeneratorManager {
+	return &GeneratorManager{
+		ctx:        ctx,
+		conn:       conn,
+		defaults:   defaults,
+		bus:        newSignalBus(),
+		monitor:    newConnMonitor(ctx, conn),
+		generators: make(map[string]*managedGenerator),
+	}
+}
+
+// Add creates a generator from spec and starts streaming. The first
+// generator added becomes the primary one.
+func (m *GeneratorManager) Add(spec domain.GeneratorSpec) (*GeneratorService, error) {
+	cfg := m.defaults
+	cfg.ID = spec.ID
+	if cfg.ID == "" {
+		cfg.ID = spec.SensorType
+	}
+	if err := domain.ValidateGeneratorID(cfg.ID); err != nil {
+		return nil, err
+	}
+	cfg.SensorType = spec.SensorType
+	if spec.FrequencyMs > 0 {
+		cfg.FrequencyMs = spec.FrequencyMs
+	}
+	if len(spec.Devices) > 0 {
+		cfg.Devices = spec.Devices
+	} else if spec.DeviceCount > 0 {
+		cfg.Devices = domain.DefaultFleet(spec.DeviceCount)
+	}
+	if spec.Seed != nil {
+		cfg.Seed = spec.Seed
+	}
+	if spec.StartTime != nil {
+		cfg.StartTime = spec.StartTime
+	}
+	if spec.Batch != nil {
+		cfg.Batch = *spec.Batch
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.generators[cfg.ID]; exists {
+		return nil, fmt.Errorf("generator %q already exists", cfg.ID)
+	}
+
+	svc, err := NewGeneratorService(m.conn, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Release the disk buffer unless the generator gets registered.
+	registered := false
+	defer func() {
+		if !registered {
+			svc.Close()
+		}
+	}()
+	svc.bus = m.bus
+	svc.sim.bus = m.bus
+	svc.sim.generatorID = cfg.ID
+	svc.out.monitor = m.monitor
+	if err := svc.UpdateDevices(cfg.Devices); err != nil {
+		return nil, err
+	}
+	if spec.ValueModel != nil {
+		if err := svc.UpdateValueModel(*spec.ValueModel); err != nil {
+			return nil, fmt.Errorf("value_model: %w", err)
+		}
+	}
+	if spec.Faults != nil {
+		svc.UpdateFaults(*spec.Faults)
+	}
+	if spec.RatePerSecond > 0 {
+		svc.SetRate(spec.RatePerSecond)
+	}
+	if spec.Load != nil {
+		if err := svc.SetLoadProfile(spec.Load); err != nil {
+			return nil, fmt.Errorf("load: %w", err)
+		}
+	}
+	if spec.Correlation != nil {
+		if err := svc.UpdateCorrelation(*spec.Correlation); err != nil {
+			return nil, fmt.Errorf("correlation: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	g := &managedGenerator{service: svc, cancel: cancel, done: make(chan struct{})}
+	m.generators[cfg.ID] = g
+	registered = true
+	if m.primary == "" {
+		m.primary = cfg.ID
+	}
+
+	g.start(ctx)
+	log.Printf("generator %q (%s) started", cfg.ID, cfg.SensorType)
+	return svc, nil
+}
+
+func (g *managedGenerator) start(ctx context.Context) {
+	done := g.done
+	go func() {
+		defer close(done)
+		run(ctx, g.service)
+	}()
+}
+
+// run keeps a generator running until ctx is cancelled. The stream itself
+// reconnects inside StartGenerator; this only restarts the generator should
+// it fail, with the same backoff.
+func run(ctx context.Context, svc *GeneratorService) {
+	bo := newBackoff(reconnectBase, reconnectMax)
+	for ctx.Err() == nil {
+		if err := svc.StartGenerator(ctx); err != nil && ctx.Err() == nil {
+			delay := bo.next()
+			log.Printf("generator %q error: %v, retrying in %v", svc.ID(), err, delay.Round(time.Millisecond))
+			sleepUntil(ctx, time.Now().Add(delay))
+		}
+	}
+}
+
+// Remove stops a generator and waits for its stream to close. Removing the
+// primary generator promotes the remaining one with the lowest ID.
+func (m *GeneratorManager) Remove(id string) error {
+	m.mu.Lock()
+	g, ok := m.generators[id]
+	if ok {
+		delete(m.generators, id)
+		if m.primary == id {
+			m.primary = ""
+			for other := range m.generators {
+				if m.primary == "" || other < m.primary {
+					m.primary = other
+				}
+			}
+			if m.primary != "" {
+				log.Printf("generator %q is now the primary generator", m.primary)
+			}
+		}
+	}
+	m.mu.Unlock()
+	if !ok {
+		return ErrGeneratorNotFound
+	}
+
+	g.service.stopSchedule()
+	g.cancel()
+	<-g.done
+	if err := g.service.Close(); err != nil {
+		log.Printf("generator %q: closing buffer: %v", id, err)
+	}
+	m.bus.forget(id, g.service.Info().SensorType)
+	log.Printf("generator %q stopped", id)
+	return nil
+}
+
+// Pause suspends emission of the generator with the given ID (or the
+// primary); its stream stays open.
+func (m *GeneratorManager) Pause(id string) (ControlStatus, error) {
+	svc, err := m.Get(id)
+	if err != nil {
+		return ControlStatus{}, err
+	}
+	if err := svc.Pause(); err != nil {
+		return svc.Control(), err
+	}
+	log.Printf("generator %q paused", svc.ID())
+	return svc.Control(), nil
+}
+
+// Resume continues a paused generator or restarts a stopped one, which
+// opens a new stream and drains anything left in its buffer.
+func (m *GeneratorManager) Resume(id string) (ControlStatus, error) {
+	m.mu.Lock()
+	if id == "" {
+		id = m.primary
+	}
+	g, ok := m.generators[id]
+	if !ok {
+		m.mu.Unlock()
+		return ControlStatus{}, ErrGeneratorNotFound
+	}
+	svc := g.service
+	if state, _ := svc.control.status(); state == StateStopped {
+		if err := svc.control.transition(StateRunning, StateStopped); err != nil {
+			m.mu.Unlock()
+			return svc.Control(), err
+		}
+		ctx, cancel := context.WithCancel(m.ctx)
+		g.cancel = cancel
+		g.done = make(chan struct{})
+		g.start(ctx)
+		m.mu.Unlock()
+		log.Printf("generator %q restarted", id)
+		return svc.Control(), nil
+	}
+	m.mu.Unlock()
+
+	if err := svc.Resume(); err != nil {
+		return svc.Control(), err
+	}
+	log.Printf("generator %q resumed", id)
+	return svc.Control(), nil
+}
+
+// Stop ends the generator's emission and closes its stream gracefully,
+// waiting for microservice-b's WriteAck. The generator keeps its
+// configuration and can be restarted with Resume.
+func (m *GeneratorManager) Stop(id string) (ControlStatus, error) {
+	m.mu.Lock()
+	if id == "" {
+		id = m.primary
+	}
+	g, ok := m.generators[id]
+	if !ok {
+		m.mu.Unlock()
+		return ControlStatus{}, ErrGeneratorNotFound
+	}
+	svc, cancel, done := g.service, g.cancel, g.done
+	m.mu.Unlock()
+
+	if state, _ := svc.control.status(); state == StateStopped {
+		return svc.Control(), nil
+	}
+	if err := svc.control.transition(StateStopping, StateRunning, StatePaused); err != nil {
+		return svc.Control(), err
+	}
+	cancel()
+	<-done
+	svc.control.transition(StateStopped, StateStopping)
+	log.Printf("generator %q stopped", id)
+	return svc.Control(), nil
+}
+
+// IDs returns the IDs of all generators, sorted.
+func (m *GeneratorManager) IDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, 0, len(m.generators))
+	for id := range m.generators {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Controls returns the state of every generator, keyed by ID.
+func (m *GeneratorManager) Controls() map[string]ControlStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]ControlStatus, len(m.generators))
+	for id, g := range m.generators {
+		out[id] = g.service.Control()
+	}
+	return out
+}
+
+// Get returns the generator with the given ID, or the primary generator
+// when id is empty.
+func (m *GeneratorManager) Get(id string) (*GeneratorService, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == "" {
+		id = m.primary
+	}
+	g, ok := m.generators[id]
+	if !ok {
+		return nil, ErrGeneratorNotFound
+	}
+	return g.service, nil
+}
+
+func (m *GeneratorManager) List() []GeneratorInfo {
+	m.mu.Lock()
+	services := make([]*GeneratorService, 0, len(m.generators))
+	for _, g := range m.generators {
+		services = append(services, g.service)
+	}
+	primary := m.primary
+	m.mu.Unlock()
+
+	infos := make([]GeneratorInfo, len(services))
+	for i, svc := range services {
+		infos[i] = svc.Info()
+		infos[i].Primary = infos[i].ID == primary
+	}
+	sort.Slice(infos, func(a, b int) bool { return infos[a].ID < infos[b].ID })
+	return infos
+}
+
+// Info returns a summary of the generator with the given ID (or the primary).
+func (m *GeneratorManager) Info(id string) (GeneratorInfo, error) {
+	m.mu.Lock()
+	if id == "" {
+		id = m.primary
+	}
+	g, ok := m.generators[id]
+	primary := m.primary
+	m.mu.Unlock()
+	if !ok {
+		return GeneratorInfo{}, ErrGeneratorNotFound
+	}
+	info := g.service.Info()
+	info.Primary = info.ID == primary
+	return info, nil
+}
+
+// Stats returns the emission stats of every generator, ordered by ID.
+func (m *GeneratorManager) Stats() []GeneratorStats {
+	m.mu.Lock()
+	services := make([]*GeneratorService, 0, len(m.generators))
+	for _, g := range m.generators {
+		services = append(services, g.service)
+	}
+	m.mu.Unlock()
+
+	stats := make([]GeneratorStats, len(services))
+	for i, svc := range services {
+		stats[i] = svc.Stats()
+	}
+	sort.Slice(stats, func(a, b int) bool { return stats[a].ID < stats[b].ID })
+	return stats
+}
+
+// Connection reports the state of the shared gRPC connection.
+func (m *GeneratorManager) Connection() ConnStatus {
+	return m.monitor.status()
+}
+
+// Senders returns the stream status of every generator, keyed by ID.
+func (m *GeneratorManager) Senders() map[string]SenderStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]SenderStatus, len(m.generators))
+	for id, g := range m.generators {
+		out[id] = g.service.out.status()
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newTestManager(t *testing.T, dir string) *GeneratorManager {
+	t.Helper()
+	conn, err := grpc.NewClient("passthrough:///microservice-b", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewGeneratorManager(ctx, conn, domain.GeneratorConfig{
+		FrequencyMs: 1000,
+		Devices:     domain.DefaultFleet(1),
+		Buffer:      domain.BufferConfig{Dir: dir},
+	})
+	t.Cleanup(func() {
+		for _, id := range m.IDs() {
+			m.Remove(id)
+		}
+		cancel()
+		conn.Close()
+	})
+	return m
+}
+
+func TestRemovePrimaryPromotesLowestID(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+	for _, id := range []string{"main", "co2", "humidity"} {
+		if _, err := m.Add(domain.GeneratorSpec{ID: id, SensorType: "temperature"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Remove("main"); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := m.Get("")
+	if err != nil {
+		t.Fatalf("no primary generator after removing it: %v", err)
+	}
+	if svc.ID() != "co2" {
+		t.Errorf("primary is %q, want co2", svc.ID())
+	}
+}
+
+// openFilesUnder counts the files under dir this process has open.
+func openFilesUnder(t *testing.T, dir string) int {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open files cannot be listed on this system")
+	}
+	n := 0
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAddFailureReleasesBuffer(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+	bad := domain.GeneratorSpec{
+		ID:         "temp",
+		SensorType: "temperature",
+		ValueModel: &domain.ValueModelConfig{Type: "no-such-model"},
+	}
+	if _, err := m.Add(bad); err == nil {
+		t.Fatal("Add accepted an invalid value model")
+	}
+	if _, err := m.Get("temp"); err == nil {
+		t.Fatal("failed generator was registered")
+	}
+	if n := openFilesUnder(t, dir); n != 0 {
+		t.Fatalf("%d buffer files left open by the failed generator", n)
+	}
+	if _, err := m.Add(domain.GeneratorSpec{ID: "temp", SensorType: "temperature"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveKeepsOtherGeneratorsSignals(t *testing.T) {
+	m := newTestManager(t, "")
+	north, south := domain.Device{ID1: "N", ID2: 1}, domain.Device{ID1: "S", ID2: 1}
+	for id, d := range map[string]domain.Device{"north": north, "south": south} {
+		spec := domain.GeneratorSpec{ID: id, SensorType: "temperature", Devices: []domain.Device{d}}
+		if _, err := m.Add(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, okN := m.bus.device("temperature", north)
+		_, okS := m.bus.device("temperature", south)
+		if okN && okS {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("generators did not publish their values")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := m.Remove("north"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.bus.device("temperature", north); ok {
+		t.Error("removed generator's value is still published")
+	}
+	if _, ok := m.bus.device("temperature", south); !ok {
+		t.Error("removing north dropped south's temperature")
+	}
+}
+
+func TestManagerPauseStopResume(t *testing.T) {
+	m := newTestManager(t, "")
+	if _, err := m.Add(domain.GeneratorSpec{ID: "temp", SensorType: "temperature"}); err != nil {
+		t.Fatal(err)
+	}
+	step := func(name string, op func(string) (ControlStatus, error), want GeneratorState, wantErr error) {
+		t.Helper()
+		status, err := op("temp")
+		if !errors.Is(err, wantErr) || status.State != want {
+			t.Fatalf("%s: %v, state %s; want %v, state %s", name, err, status.State, wantErr, want)
+		}
+	}
+
+	step("pause", m.Pause, StatePaused, nil)
+	step("pause again", m.Pause, StatePaused, nil)
+	step("stop while paused", m.Stop, StateStopped, nil)
+	step("stop again", m.Stop, StateStopped, nil)
+	step("pause while stopped", m.Pause, StateStopped, ErrInvalidTransition)
+	step("restart", m.Resume, StateRunning, nil)
+	step("resume while running", m.Resume, StateRunning, nil)
+	step("stop while running", m.Stop, StateStopped, nil)
+	step("restart again", m.Resume, StateRunning, nil)
+	step("pause after restart", m.Pause, StatePaused, nil)
+	step("resume", m.Resume, StateRunning, nil)
+
+	for name, op := range map[string]func(string) (ControlStatus, error){"pause": m.Pause, "resume": m.Resume, "stop": m.Stop} {
+		if _, err := op("missing"); !errors.Is(err, ErrGeneratorNotFound) {
+			t.Errorf("%s of an unknown generator: %v, want ErrGeneratorNotFound", name, err)
+		}
+	}
+}
@@ -2,8 +2,7 @@ package service
 
 import (
 	"context"
-	"log"
-	"math/rand"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -15,53 +14,442 @@ import (
 type GeneratorService struct {
 	client     pb.IngestServiceClient
 	config     *domain.GeneratorConfig
-	frequency  *int64
+	interval   *int64 // nanoseconds between ticks
+	bus        *signalBus
+	stats      *generatorStats
+	out        *sender
+	control    *control
+	startedAt  time.Time
+
+	// emitMu is held while a tick is produced and sent, so Pause can wait
+	// for the tick in flight.
+	emitMu sync.Mutex
+
+	// loadMu guards the pacing mode (fixed interval, rate target or load
+	// profile) and writes to interval so the modes never overwrite each other.
+	loadMu sync.Mutex
+	rate   float64 // target readings per second, 0 for a fixed interval
+	load   *loadSchedule
+	// wake interrupts the emission loop's sleep when the pacing changes.
+	wake chan struct{}
+
+	mu             sync.Mutex
+	sim            *simulation
+	scenario       *domain.Scenario
+	phase          int
+	scheduleCancel context.CancelFunc
 }
 
-func NewGeneratorService(conn *grpc.ClientConn, config *domain.GeneratorConfig) *GeneratorService {
-	freq := config.FrequencyMs
-	return &GeneratorService{
-		client:    pb.NewIngestServiceClient(conn),
+func NewGeneratorService(conn *grpc.ClientConn, config *domain.GeneratorConfig) (*GeneratorService, error) {
+	client := pb.NewIngestServiceClient(conn)
+	var buf *diskBuffer
+	if config.Buffer.Dir != "" {
+		dir, err := config.Buffer.DirFor(config.ID)
+		if err != nil {
+			return nil, err
+		}
+		buf, err = openDiskBuffer(dir, config.Buffer.MaxBytes, config.Buffer.Overflow)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	interval := int64(time.Duration(config.FrequencyMs) * time.Millisecond)
+	s := &GeneratorService{
+		client:    client,
 		config:    config,
-		frequency: &freq,
+		interval:  &interval,
+		stats:     newGeneratorStats(),
+		out:       newSender(config.ID, client, buf, config.Batch),
+		control:   newControl(),
+		startedAt: time.Now(),
+		wake:      make(chan struct{}, 1),
+		sim: newSimulation(
+			config.SensorType,
+			config.UnitFor(config.SensorType),
+			config.ValueModelFor(config.SensorType),
+			domain.FaultConfig{},
+			config.Devices,
+		),
+	}
+
+	start := domain.DefaultLogicalStart
+	if config.StartTime != nil {
+		start = *config.StartTime
 	}
+	if err := s.SetSeed(config.Seed, start); err != nil {
+		s.out.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close releases the disk buffer. The generator must have stopped.
+func (s *GeneratorService) Close() error {
+	return s.out.close()
 }
 
+// UpdateFrequency sets a fixed interval in milliseconds, replacing any rate
+// target or load profile.
 func (s *GeneratorService) UpdateFrequency(freq int64) {
-	atomic.StoreInt64(s.frequency, freq)
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	s.rate = 0
+	s.load = nil
+	atomic.StoreInt64(s.interval, int64(time.Duration(freq)*time.Millisecond))
+	s.signalWake()
 }
 
+// SetRate targets a number of readings per second across the fleet,
+// replacing any fixed interval or load profile. The tick interval follows
+// the device count, so it may be well below a millisecond.
+func (s *GeneratorService) SetRate(readingsPerSecond float64) {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	s.rate = readingsPerSecond
+	s.load = nil
+	s.signalWake()
+}
+
+// GetRate returns the rate target, or 0 when pacing by interval.
+func (s *GeneratorService) GetRate() float64 {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	return s.rate
+}
+
+// GetFrequency returns the tick interval in whole milliseconds; see
+// GetInterval for sub-millisecond intervals.
 func (s *GeneratorService) GetFrequency() int64 {
-	return atomic.LoadInt64(s.frequency)
+	return s.GetInterval().Milliseconds()
 }
 
-func (s *GeneratorService) StartGenerator(ctx context.Context) error {
-	stream, err := s.client.Write(ctx)
-	if err != nil {
+func (s *GeneratorService) GetInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(s.interval))
+}
+
+func (s *GeneratorService) signalWake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SetLoadProfile shapes the frequency over time from now on. A nil profile
+// stops shaping and keeps the current frequency.
+func (s *GeneratorService) SetLoadProfile(p *domain.LoadProfile) error {
+	var l *loadSchedule
+	if p != nil {
+		var err error
+		if l, err = newLoadSchedule(*p, time.Now()); err != nil {
+			return err
+		}
+	}
+	s.setLoad(l)
+	return nil
+}
+
+func (s *GeneratorService) setLoad(l *loadSchedule) {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	s.load = l
+	if l != nil {
+		s.rate = 0
+		atomic.StoreInt64(s.interval, int64(time.Duration(l.frequencyAt(time.Now()))*time.Millisecond))
+		s.signalWake()
+	}
+}
+
+// pacing is a snapshot of the pacing mode: a fixed interval, a rate target
+// or a load profile.
+type pacing struct {
+	interval int64
+	rate     float64
+	load     *loadSchedule
+}
+
+func (s *GeneratorService) currentPacing() pacing {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	return pacing{interval: atomic.LoadInt64(s.interval), rate: s.rate, load: s.load}
+}
+
+// setPacing restores a pacing mode taken by currentPacing.
+func (s *GeneratorService) setPacing(p pacing) {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	s.rate = p.rate
+	s.load = p.load
+	atomic.StoreInt64(s.interval, p.interval)
+	s.signalWake()
+}
+
+func (s *GeneratorService) GetLoadProfile() LoadStatus {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	status := LoadStatus{FrequencyMs: s.GetFrequency()}
+	if s.load != nil {
+		profile, start := s.load.profile, s.load.start
+		status.Profile = &profile
+		status.StartedAt = &start
+	}
+	return status
+}
+
+// minInterval bounds the interval derived from a rate target.
+const minInterval = time.Microsecond
+
+// refreshIntervalLocked recomputes the interval from the load profile or
+// rate target, if any, and returns it. s.mu must be held.
+func (s *GeneratorService) refreshIntervalLocked(now time.Time) time.Duration {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	switch {
+	case s.load != nil:
+		atomic.StoreInt64(s.interval, int64(time.Duration(s.load.frequencyAt(now))*time.Millisecond))
+	case s.rate > 0:
+		interval := time.Duration(float64(len(s.sim.devices)) / s.rate * float64(time.Second))
+		if interval < minInterval {
+			interval = minInterval
+		}
+		atomic.StoreInt64(s.interval, int64(interval))
+	}
+	return s.GetInterval()
+}
+
+// CurrentInterval returns the interval the next tick will use.
+func (s *GeneratorService) CurrentInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshIntervalLocked(time.Now())
+}
+
+// Pause stops emitting readings without closing the stream. When it
+// returns, no further readings are produced until Resume.
+func (s *GeneratorService) Pause() error {
+	if err := s.control.transition(StatePaused, StateRunning); err != nil {
 		return err
 	}
+	s.emitMu.Lock()
+	s.emitMu.Unlock()
+	return nil
+}
 
-	idPool := []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J"}
+// Resume continues a paused generator from the current time, without
+// catching up on the ticks missed while paused. Stopped generators are
+// restarted by GeneratorManager.Resume.
+func (s *GeneratorService) Resume() error {
+	return s.control.transition(StateRunning, StatePaused)
+}
 
+// Control returns the generator's state and last stream acknowledgement.
+func (s *GeneratorService) Control() ControlStatus {
+	state, since := s.control.status()
+	return ControlStatus{
+		ID:      s.config.ID,
+		State:   state,
+		Since:   since,
+		LastAck: s.out.status().LastAck,
+	}
+}
+
+func (s *GeneratorService) ID() string {
+	return s.config.ID
+}
+
+func (s *GeneratorService) Info() GeneratorInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return GeneratorInfo{
+		ID:            s.config.ID,
+		SensorType:    s.sim.sensorType,
+		Unit:          s.sim.unit,
+		FrequencyMs:   s.GetFrequency(),
+		IntervalUs:    float64(s.GetInterval()) / float64(time.Microsecond),
+		RatePerSecond: s.GetRate(),
+		Devices:       s.sim.deviceList(),
+		Seed:          s.sim.seed,
+		Deterministic: s.sim.deterministic,
+		Batch:         s.out.batch,
+		State:         s.Control().State,
+	}
+}
+
+// UpdateValueModel replaces the value model used for new readings. The model
+// state of every device (e.g. random walk position) starts over.
+func (s *GeneratorService) UpdateValueModel(cfg domain.ValueModelConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.setValueModel(cfg)
+}
+
+func (s *GeneratorService) GetValueModel() domain.ValueModelConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.modelCfg
+}
+
+// SetSeed restarts the reading sequence. With a seed, values, ID choices and
+// timestamps (from a logical clock beginning at start) are reproducible.
+// A nil seed picks a time-based seed and uses wall-clock timestamps.
+func (s *GeneratorService) SetSeed(seed *int64, start time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.reseed(seed, start)
+}
+
+// GetSeed returns the active seed and whether the run is deterministic.
+func (s *GeneratorService) GetSeed() (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.seed, s.sim.deterministic
+}
+
+func (s *GeneratorService) UpdateFaults(cfg domain.FaultConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sim.setFaults(cfg)
+}
+
+func (s *GeneratorService) GetFaults() (domain.FaultConfig, map[domain.FaultKind]uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.faultCfg, s.sim.faultCountsCopy()
+}
+
+// UpdateDevices replaces the fleet. Devices that were already present keep
+// their value model state; new devices start fresh.
+func (s *GeneratorService) UpdateDevices(devices []domain.Device) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.setDevices(devices)
+}
+
+func (s *GeneratorService) GetDevices() []domain.Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.deviceList()
+}
+
+// UpdateCorrelation ties this generator's values to other generators in the
+// same process. An empty config removes the correlation.
+func (s *GeneratorService) UpdateCorrelation(cfg domain.CorrelationConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.setCorrelation(cfg)
+}
+
+// GetCorrelation returns the correlation config and how many of its results
+// since it was set were not finite numbers and fell back to the model value.
+func (s *GeneratorService) GetCorrelation() (domain.CorrelationConfig, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sim.correlation()
+}
+
+// forkSimulation returns an independent simulation with the current
+// configuration whose logical clock starts at start. A deterministic
+// generator forks with its own seed, so backfills are reproducible too.
+// Forks are not attached to the signal bus, so they are uncorrelated.
+func (s *GeneratorService) forkSimulation(start time.Time) (*simulation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sim := newSimulation(s.sim.sensorType, s.sim.unit, s.sim.modelCfg, s.sim.faultCfg, s.sim.deviceList())
+	seed := time.Now().UnixNano()
+	if s.sim.deterministic {
+		seed = s.sim.seed
+	}
+	if err := sim.reseed(&seed, start); err != nil {
+		return nil, err
+	}
+	return sim, nil
+}
+
+// nextReadings produces one tick and returns the interval until the next.
+func (s *GeneratorService) nextReadings() ([]*pb.Reading, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	interval := s.refreshIntervalLocked(time.Now())
+	return s.sim.tick(interval), interval
+}
+
+// maxScheduleLag is how far the emission loop may fall behind its schedule
+// before skipping ticks instead of sending a catch-up burst.
+const maxScheduleLag = time.Second
+
+// StartGenerator streams readings until ctx is cancelled. Ticks are
+// scheduled against the monotonic clock rather than sleeping after each
+// send, so send latency does not slow the rate down; ticks that fall due
+// while sending are emitted back to back, which also makes intervals shorter
+// than the timer resolution achievable. Generation continues while
+// microservice-b is unreachable; readings are then buffered on disk.
+// The stream is closed gracefully once ctx is cancelled.
+func (s *GeneratorService) StartGenerator(ctx context.Context) error {
+	senderCtx, stopSender := context.WithCancel(ctx)
+	senderDone := make(chan struct{})
+	go func() {
+		defer close(senderDone)
+		s.out.run(senderCtx)
+	}()
+	defer func() {
+		stopSender()
+		<-senderDone
+	}()
+
+	next := time.Now()
+	last := next
 	for {
 		select {
 		case <-ctx.Done():
-			return stream.CloseSend()
+			return nil
 		default:
-			now := time.Now().UTC().Format(time.RFC3339Nano)
-			msg := &pb.Reading{
-				Value:      rand.Float64() * 100,
-				SensorType: s.config.SensorType,
-				Id1:        idPool[rand.Intn(len(idPool))],
-				Id2:        int32(rand.Intn(100)),
-				Timestamp:  now,
+		}
+
+		if state, changed := s.control.current(); state == StatePaused {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-changed:
 			}
-			
-			if err := stream.Send(msg); err != nil {
-				log.Printf("send error: %v", err)
+			next = time.Now()
+			last = next
+			continue
+		}
+
+		now := time.Now()
+		if behind := now.Sub(next); behind > maxScheduleLag {
+			interval := s.GetInterval()
+			skipped := int64(behind / interval)
+			s.stats.skip(uint64(skipped))
+			next = next.Add(time.Duration(skipped) * interval)
+		}
+
+		if next.After(now) {
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-s.wake:
+				// The pacing changed; reschedule from the last tick.
+				timer.Stop()
+				next = last.Add(s.CurrentInterval())
+			case <-timer.C:
 			}
-			
-			time.Sleep(time.Duration(s.GetFrequency()) * time.Millisecond)
+			continue
+		}
+
+		s.emitMu.Lock()
+		if state, _ := s.control.current(); state == StatePaused {
+			s.emitMu.Unlock()
+			continue
 		}
+		msgs, interval := s.nextReadings()
+		s.out.write(msgs)
+		s.stats.tick(len(msgs), time.Now())
+		s.emitMu.Unlock()
+		last = next
+		next = next.Add(interval)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+)
+
+// LoadStatus reports the active load profile and the frequency it
+// currently produces.
+type LoadStatus struct {
+	Profile     *domain.LoadProfile `json:"profile"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	FrequencyMs int64               `json:"frequency_ms"`
+}
+
+// loadSchedule computes the frequency a LoadProfile prescribes at a given
+// wall-clock time.
+type loadSchedule struct {
+	profile domain.LoadProfile
+	start   time.Time
+	loc     *time.Location
+	windows []*cronExpr
+}
+
+// newLoadSchedule validates p and anchors its relative timing at start.
+func newLoadSchedule(p domain.LoadProfile, start time.Time) (*loadSchedule, error) {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", p.Timezone, err)
+	}
+	l := &loadSchedule{profile: p, start: start, loc: loc}
+
+	switch p.Type {
+	case domain.LoadRamp:
+		if p.FromMs <= 0 || p.ToMs <= 0 || p.DurationSeconds <= 0 {
+			return nil, fmt.Errorf("ramp profile requires from_ms, to_ms and a positive duration_seconds")
+		}
+	case domain.LoadSteps:
+		if len(p.Steps) == 0 {
+			return nil, fmt.Errorf("steps profile requires at least one step")
+		}
+		for i, s := range p.Steps {
+			if s.FrequencyMs <= 0 || s.DurationSeconds <= 0 {
+				return nil, fmt.Errorf("steps[%d]: frequency_ms and duration_seconds must be positive", i)
+			}
+		}
+	case domain.LoadBurst:
+		if p.BaseMs <= 0 || p.BurstMs <= 0 || p.PeriodSeconds <= 0 || p.BurstSeconds <= 0 {
+			return nil, fmt.Errorf("burst profile requires base_ms, burst_ms, period_seconds and burst_seconds")
+		}
+		if p.BurstSeconds > p.PeriodSeconds {
+			return nil, fmt.Errorf("burst_seconds %v exceeds period_seconds %v", p.BurstSeconds, p.PeriodSeconds)
+		}
+	case domain.LoadDiurnal:
+		if p.PeakMs <= 0 || p.TroughMs <= 0 {
+			return nil, fmt.Errorf("diurnal profile requires peak_ms and trough_ms")
+		}
+	case domain.LoadWindows:
+		if p.BaseMs <= 0 || len(p.Windows) == 0 {
+			return nil, fmt.Errorf("windows profile requires base_ms and at least one window")
+		}
+		for i, w := range p.Windows {
+			expr, err := parseCron(w.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("windows[%d]: %w", i, err)
+			}
+			l.windows = append(l.windows, expr)
+		}
+	default:
+		return nil, fmt.Errorf("unknown load profile type %q", p.Type)
+	}
+	return l, nil
+}
+
+// frequencyAt returns the interval in milliseconds to use at now.
+func (l *loadSchedule) frequencyAt(now time.Time) int64 {
+	p := l.profile
+	elapsed := now.Sub(l.start).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	switch p.Type {
+	case domain.LoadRamp:
+		if p.Repeat {
+			elapsed = math.Mod(elapsed, p.DurationSeconds)
+		}
+		return interpolateInterval(p.FromMs, p.ToMs, math.Min(elapsed/p.DurationSeconds, 1))
+	case domain.LoadSteps:
+		var total float64
+		for _, s := range p.Steps {
+			total += s.DurationSeconds
+		}
+		if p.Repeat {
+			elapsed = math.Mod(elapsed, total)
+		}
+		for _, s := range p.Steps {
+			if elapsed < s.DurationSeconds {
+				return s.FrequencyMs
+			}
+			elapsed -= s.DurationSeconds
+		}
+		return p.Steps[len(p.Steps)-1].FrequencyMs
+	case domain.LoadBurst:
+		if math.Mod(elapsed, p.PeriodSeconds) < p.BurstSeconds {
+			return p.BurstMs
+		}
+		return p.BaseMs
+	case domain.LoadDiurnal:
+		local := now.In(l.loc)
+		hour := float64(local.Hour()) + float64(local.Minute())/60 + float64(local.Second())/3600
+		// 1 at the peak hour, 0 twelve hours later.
+		frac := (1 + math.Cos(2*math.Pi*(hour-p.PeakHour)/24)) / 2
+		return interpolateInterval(p.TroughMs, p.PeakMs, frac)
+	default: // windows
+		local := now.In(l.loc)
+		for i, w := range l.windows {
+			if w.matches(local) {
+				return p.Windows[i].FrequencyMs
+			}
+		}
+		return p.BaseMs
+	}
+}
+
+// interpolateInterval moves linearly in rate (readings per second) from
+// fromMs to toMs as frac goes from 0 to 1, so a ramp adds traffic evenly.
+func interpolateInterval(fromMs, toMs int64, frac float64) int64 {
+	from, to := 1/float64(fromMs), 1/float64(toMs)
+	return int64(math.Round(1 / (from + (to-from)*frac)))
+}
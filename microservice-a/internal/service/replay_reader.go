@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+)
+
+// replayRecord is one row of a recorded trace.
+type replayRecord struct {
+	reading *pb.Reading
+	ts      time.Time
+	line    int // in the file, counting from 1
+}
+
+// replayReader yields records one at a time so large traces are never held
+// in memory. Next returns io.EOF at the end of the input.
+type replayReader interface {
+	Next() (replayRecord, error)
+}
+
+func columnName(name, fallback string) string {
+	if name != "" {
+		return name
+	}
+	return fallback
+}
+
+// rowMapper converts a row, accessed by column name, into a record.
+type rowMapper struct {
+	cols       domain.ReplayColumns
+	sensorType string
+}
+
+func newRowMapper(cols domain.ReplayColumns, sensorType string) rowMapper {
+	return rowMapper{
+		cols: domain.ReplayColumns{
+			Value:      columnName(cols.Value, "value"),
+			SensorType: columnName(cols.SensorType, "sensor_type"),
+			ID1:        columnName(cols.ID1, "id1"),
+			ID2:        columnName(cols.ID2, "id2"),
+			Timestamp:  columnName(cols.Timestamp, "timestamp"),
+			Unit:       columnName(cols.Unit, "unit"),
+		},
+		sensorType: sensorType,
+	}
+}
+
+func (m rowMapper) record(get func(string) (string, bool)) (replayRecord, error) {
+	valueStr, ok := get(m.cols.Value)
+	if !ok {
+		return replayRecord{}, fmt.Errorf("missing %q column", m.cols.Value)
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return replayRecord{}, fmt.Errorf("invalid value %q", valueStr)
+	}
+
+	id1, ok := get(m.cols.ID1)
+	if !ok {
+		return replayRecord{}, fmt.Errorf("missing %q column", m.cols.ID1)
+	}
+	id2Str, ok := get(m.cols.ID2)
+	if !ok {
+		return replayRecord{}, fmt.Errorf("missing %q column", m.cols.ID2)
+	}
+	id2, err := strconv.Atoi(id2Str)
+	if err != nil {
+		return replayRecord{}, fmt.Errorf("invalid id2 %q", id2Str)
+	}
+
+	tsStr, ok := get(m.cols.Timestamp)
+	if !ok {
+		return replayRecord{}, fmt.Errorf("missing %q column", m.cols.Timestamp)
+	}
+	ts, err := parseReplayTimestamp(tsStr)
+	if err != nil {
+		return replayRecord{}, err
+	}
+
+	sensorType, ok := get(m.cols.SensorType)
+	if !ok || sensorType == "" {
+		sensorType = m.sensorType
+	}
+	unit, ok := get(m.cols.Unit)
+	if !ok {
+		if p, found := domain.LookupSensorProfile(sensorType); found {
+			unit = p.Unit
+		}
+	}
+
+	return replayRecord{
+		reading: &pb.Reading{
+			Value:      value,
+			SensorType: sensorType,
+			Unit:       unit,
+			Id1:        id1,
+			Id2:        int32(id2),
+			Timestamp:  ts.Format(time.RFC3339Nano),
+		},
+		ts: ts,
+	}, nil
+}
+
+// parseReplayTimestamp accepts RFC3339 or Unix seconds (with fraction).
+func parseReplayTimestamp(s string) (time.Time, error) {
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts.UTC(), nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+type csvReplayReader struct {
+	r      *csv.Reader
+	mapper rowMapper
+	header map[string]int
+	line   int
+}
+
+func newCSVReplayReader(r io.Reader, mapper rowMapper) (*csvReplayReader, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+	idx := make(map[string]int, len(header))
+	for i, name := range header {
+		idx[strings.TrimSpace(name)] = i
+	}
+	return &csvReplayReader{r: cr, mapper: mapper, header: idx, line: 1}, nil
+}
+
+func (c *csvReplayReader) Next() (replayRecord, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		return replayRecord{}, err
+	}
+	c.line++
+	rec, err := c.mapper.record(func(name string) (string, bool) {
+		i, ok := c.header[name]
+		if !ok || i >= len(row) {
+			return "", false
+		}
+		return row[i], true
+	})
+	if err != nil {
+		return replayRecord{}, fmt.Errorf("line %d: %w", c.line, err)
+	}
+	rec.line = c.line
+	return rec, nil
+}
+
+type ndjsonReplayReader struct {
+	s      *bufio.Scanner
+	mapper rowMapper
+	line   int
+}
+
+func newNDJSONReplayReader(r io.Reader, mapper rowMapper) *ndjsonReplayReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &ndjsonReplayReader{s: s, mapper: mapper}
+}
+
+func (n *ndjsonReplayReader) Next() (replayRecord, error) {
+	for n.s.Scan() {
+		n.line++
+		line := strings.TrimSpace(n.s.Text())
+		if line == "" {
+			continue
+		}
+
+		var row map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			return replayRecord{}, fmt.Errorf("line %d: %w", n.line, err)
+		}
+		rec, err := n.mapper.record(func(name string) (string, bool) {
+			v, ok := row[name]
+			if !ok || v == nil {
+				return "", false
+			}
+			switch v := v.(type) {
+			case string:
+				return v, true
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64), true
+			default:
+				return fmt.Sprint(v), true
+			}
+		})
+		if err != nil {
+			return replayRecord{}, fmt.Errorf("line %d: %w", n.line, err)
+		}
+		rec.line = n.line
+		return rec, nil
+	}
+	if err := n.s.Err(); err != nil {
+		return replayRecord{}, err
+	}
+	return replayRecord{}, io.EOF
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/grpc"
+)
+
+// ReplayService streams recorded traces from files under dir through
+// IngestService.Write, each job on its own stream.
+type ReplayService struct {
+	client     pb.IngestServiceClient
+	dir        string
+	sensorType string
+
+	mu       sync.Mutex
+	jobs     map[string]*replayJob
+	finished []string // IDs of finished jobs, oldest first
+	nextID   int
+}
+
+type replayJob struct {
+	job    domain.ReplayJob
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the job has finished
+}
+
+// NewReplayService serves files from dir. sensorType is used for rows
+// without a sensor_type column unless the request overrides it.
+func NewReplayService(conn *grpc.ClientConn, dir, sensorType string) *ReplayService {
+	return &ReplayService{
+		client:     pb.NewIngestServiceClient(conn),
+		dir:        dir,
+		sensorType: sensorType,
+		jobs:       make(map[string]*replayJob),
+	}
+}
+
+// resolve maps a request path into the replay directory, refusing paths
+// that would escape it.
+func (s *ReplayService) resolve(path string) string {
+	return filepath.Join(s.dir, filepath.Clean("/"+path))
+}
+
+// replayKeyPrefix identifies a replay file in reading_ids. It depends only on
+// the path within the replay directory, so replaying a file again produces
+// the same keys.
+func replayKeyPrefix(path string) string {
+	sum := sha256.Sum256([]byte(filepath.ToSlash(filepath.Clean("/" + path))))
+	return "rp-" + hex.EncodeToString(sum[:8])
+}
+
+func detectReplayFormat(path string) (domain.ReplayFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return domain.ReplayCSV, nil
+	case ".ndjson", ".jsonl", ".json":
+		return domain.ReplayNDJSON, nil
+	default:
+		return "", fmt.Errorf("cannot detect format of %q, set format to csv or ndjson", path)
+	}
+}
+
+func (s *ReplayService) open(path string, format domain.ReplayFormat, mapper rowMapper) (replayReader, io.Closer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if format == domain.ReplayCSV {
+		r, err := newCSVReplayReader(f, mapper)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return r, f, nil
+	}
+	return newNDJSONReplayReader(f, mapper), f, nil
+}
+
+func (s *ReplayService) Start(req domain.ReplayRequest) (domain.ReplayJob, error) {
+	if req.Format == "" {
+		format, err := detectReplayFormat(req.Path)
+		if err != nil {
+			return domain.ReplayJob{}, err
+		}
+		req.Format = format
+	}
+	if req.Pacing == "" {
+		req.Pacing = domain.ReplayOriginal
+	}
+	if req.Pacing != domain.ReplaySpeed {
+		req.Speed = 1
+	}
+	if req.SensorType == "" {
+		req.SensorType = s.sensorType
+	}
+
+	path := s.resolve(req.Path)
+	mapper := newRowMapper(req.Columns, req.SensorType)
+	// Open once up front so missing files and bad headers fail the request.
+	_, closer, err := s.open(path, req.Format, mapper)
+	if err != nil {
+		return domain.ReplayJob{}, err
+	}
+	closer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	s.nextID++
+	j := &replayJob{
+		job: domain.ReplayJob{
+			ID:        fmt.Sprintf("rp-%d", s.nextID),
+			Request:   req,
+			State:     domain.JobRunning,
+			StartedAt: time.Now().UTC(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.jobs[j.job.ID] = j
+	snapshot := j.job
+	s.mu.Unlock()
+
+	go s.run(ctx, j, path, mapper)
+	return snapshot, nil
+}
+
+func (s *ReplayService) run(ctx context.Context, j *replayJob, path string, mapper rowMapper) {
+	defer j.cancel()
+	req := j.job.Request
+
+	stream, err := s.client.Write(ctx)
+	if err != nil {
+		s.finish(ctx, j, err)
+		return
+	}
+
+	replayStart := time.Now().UTC()
+	// shift moves recorded time onto the replay timeline; it grows by one
+	// trace length per loop so looped timestamps keep moving forward.
+	var shift time.Duration
+	// Each row's reading_id is derived from the file, its line and, when
+	// timestamps are shifted, the pass. Rows re-sent after a failure, and
+	// unshifted loops, which repeat the same readings, are stored once.
+	keyPrefix := replayKeyPrefix(req.Path)
+	for pass := 0; ; pass++ {
+		reader, closer, err := s.open(path, req.Format, mapper)
+		if err != nil {
+			s.finish(ctx, j, err)
+			return
+		}
+
+		passStart := time.Now()
+		var first, prev time.Time
+		var lastGap time.Duration
+		rows := 0
+		for {
+			rec, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				closer.Close()
+				s.finish(ctx, j, err)
+				return
+			}
+
+			if rows == 0 {
+				first = rec.ts
+			} else {
+				lastGap = rec.ts.Sub(prev)
+			}
+			prev = rec.ts
+			rows++
+
+			elapsed := rec.ts.Sub(first)
+			if req.Pacing != domain.ReplayFast {
+				target := passStart.Add(time.Duration(float64(elapsed) / req.Speed))
+				if err := sleepUntil(ctx, target); err != nil {
+					closer.Close()
+					s.finish(ctx, j, err)
+					return
+				}
+			}
+			keyPass := 0
+			if req.ShiftTimestamps {
+				rec.reading.Timestamp = replayStart.Add(shift + elapsed).Format(time.RFC3339Nano)
+				keyPass = pass
+			}
+			rec.reading.ReadingId = fmt.Sprintf("%s-%d-%d", keyPrefix, keyPass, rec.line)
+
+			if err := stream.Send(rec.reading); err != nil {
+				closer.Close()
+				s.finish(ctx, j, err)
+				return
+			}
+			s.mu.Lock()
+			j.job.ReadingsSent++
+			s.mu.Unlock()
+		}
+		closer.Close()
+
+		s.mu.Lock()
+		j.job.Loops++
+		s.mu.Unlock()
+		if !req.Loop || rows == 0 {
+			break
+		}
+		if lastGap <= 0 {
+			lastGap = time.Second
+		}
+		shift += prev.Sub(first) + lastGap
+	}
+
+	ack, err := stream.CloseAndRecv()
+	if err == nil {
+		s.mu.Lock()
+		j.job.ReadingsAcked = ack.GetCount()
+		j.job.ReadingsRejected = ack.GetRejected()
+		j.job.Rejections = describeRejections(ack.GetRejections())
+		s.mu.Unlock()
+	}
+	s.finish(ctx, j, err)
+}
+
+func (s *ReplayService) finish(ctx context.Context, j *replayJob, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	j.job.FinishedAt = &now
+	switch {
+	case ctx.Err() == context.Canceled:
+		j.job.State = domain.JobCancelled
+	case err != nil:
+		j.job.State = domain.JobFailed
+		j.job.Error = err.Error()
+	default:
+		j.job.State = domain.JobCompleted
+	}
+	close(j.done)
+
+	s.finished = append(s.finished, j.job.ID)
+	if len(s.finished) > maxFinishedJobs {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+func (s *ReplayService) Get(id string) (domain.ReplayJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return domain.ReplayJob{}, false
+	}
+	return j.job, true
+}
+
+func (s *ReplayService) List() []domain.ReplayJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]domain.ReplayJob, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].StartedAt.Before(jobs[b].StartedAt) })
+	return jobs
+}
+
+// Cancel stops a running job and waits for it to finish, so the returned job
+// is in its final state. It reports false if the job does not exist.
+func (s *ReplayService) Cancel(id string) (domain.ReplayJob, bool) {
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return domain.ReplayJob{}, false
+	}
+	j.cancel()
+	<-j.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return j.job, true
+}
+
+// sleepUntil waits for t or until ctx is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/grpc"
+)
+
+// writeClient records what Write streams send. Once limit readings have
+// been sent, Send blocks until the stream's context ends.
+type writeClient struct {
+	pb.IngestServiceClient
+	limit int
+
+	mu   sync.Mutex
+	sent []*pb.Reading
+	full chan struct{}
+}
+
+func newWriteClient(limit int) *writeClient {
+	return &writeClient{limit: limit, full: make(chan struct{})}
+}
+
+func (c *writeClient) Write(ctx context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[pb.Reading, pb.WriteAck], error) {
+	return &writeStream{ctx: ctx, client: c}, nil
+}
+
+func (c *writeClient) readings() []*pb.Reading {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*pb.Reading(nil), c.sent...)
+}
+
+type writeStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	client *writeClient
+}
+
+func (s *writeStream) Send(r *pb.Reading) error {
+	c := s.client
+	c.mu.Lock()
+	if len(c.sent) == c.limit {
+		c.mu.Unlock()
+		<-s.ctx.Done()
+		return s.ctx.Err()
+	}
+	c.sent = append(c.sent, r)
+	if len(c.sent) == c.limit {
+		close(c.full)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (s *writeStream) CloseAndRecv() (*pb.WriteAck, error) {
+	return &pb.WriteAck{}, nil
+}
+
+func newTestReplayService(t *testing.T, client pb.IngestServiceClient) *ReplayService {
+	t.Helper()
+	dir := t.TempDir()
+	trace := "id1,id2,value,timestamp\nA,1,20.5,2024-01-01T00:00:00Z\nA,1,21,2024-01-01T00:01:00Z\nB,2,19,2024-01-01T00:02:00Z\n"
+	if err := os.WriteFile(filepath.Join(dir, "trace.csv"), []byte(trace), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewReplayService(nil, dir, "temperature")
+	s.client = client
+	return s
+}
+
+// replayKeys loops trace.csv until two passes are sent and returns their
+// reading_ids.
+func replayKeys(t *testing.T, shift bool) []string {
+	t.Helper()
+	client := newWriteClient(6)
+	s := newTestReplayService(t, client)
+	job, err := s.Start(domain.ReplayRequest{
+		Path:            "trace.csv",
+		Pacing:          domain.ReplayFast,
+		Loop:            true,
+		ShiftTimestamps: shift,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-client.full:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay did not send two passes")
+	}
+	if job, _ = s.Cancel(job.ID); job.State != domain.JobCancelled {
+		t.Errorf("Cancel returned state %q, want cancelled", job.State)
+	}
+
+	var keys []string
+	for _, r := range client.readings() {
+		keys = append(keys, r.ReadingId)
+	}
+	return keys
+}
+
+func TestReplayKeysRepeatForUnshiftedLoops(t *testing.T) {
+	keys := replayKeys(t, false)
+	first := map[string]bool{keys[0]: true, keys[1]: true, keys[2]: true}
+	if len(first) != 3 {
+		t.Fatalf("rows of one pass share reading_ids: %v", keys)
+	}
+	for i := 0; i < 3; i++ {
+		if keys[i] != keys[i+3] {
+			t.Errorf("row %d has key %q in the second pass, want %q", i, keys[i+3], keys[i])
+		}
+	}
+	if again := replayKeys(t, false); again[0] != keys[0] {
+		t.Errorf("replaying the file again gave key %q, want %q", again[0], keys[0])
+	}
+}
+
+func TestReplayKeysDifferForShiftedLoops(t *testing.T) {
+	keys := replayKeys(t, true)
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k == "" || len(k) > 64 || seen[k] {
+			t.Fatalf("reading_ids %v are not unique, non-empty and at most 64 bytes", keys)
+		}
+		seen[k] = true
+	}
+}
+
+func TestReplayKeepsLimitedFinishedJobs(t *testing.T) {
+	s := newTestReplayService(t, newWriteClient(-1))
+	const jobs = maxFinishedJobs + 5
+	for i := 0; i < jobs; i++ {
+		job, err := s.Start(domain.ReplayRequest{Path: "trace.csv", Pacing: domain.ReplayFast})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job, _ = s.Cancel(job.ID); job.State == domain.JobRunning {
+			t.Fatalf("Cancel returned a running job")
+		}
+	}
+	if got := len(s.List()); got != maxFinishedJobs {
+		t.Errorf("%d jobs kept, want %d", got, maxFinishedJobs)
+	}
+	if _, ok := s.Get("rp-1"); ok {
+		t.Error("the oldest finished job was kept")
+	}
+}
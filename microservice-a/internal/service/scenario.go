@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+)
+
+// ScenarioStatus reports the active scenario and, if it has a schedule,
+// the phase currently in effect.
+type ScenarioStatus struct {
+	Scenario   *domain.Scenario `json:"scenario"`
+	PhaseIndex *int             `json:"phase_index,omitempty"`
+	PhaseName  string           `json:"phase_name,omitempty"`
+}
+
+// ApplyScenario replaces the generator's simulation with one built from sc
+// and starts its schedule. On error the running configuration is untouched.
+func (s *GeneratorService) ApplyScenario(sc *domain.Scenario) error {
+	s.mu.Lock()
+	sensorType := s.sim.sensorType
+	s.mu.Unlock()
+	if sc.SensorType != "" {
+		sensorType = sc.SensorType
+	}
+
+	modelCfg := s.config.ValueModelFor(sensorType)
+	if sc.ValueModel != nil {
+		modelCfg = *sc.ValueModel
+	}
+	if err := validateValueModel(modelCfg); err != nil {
+		return fmt.Errorf("value_model: %w", err)
+	}
+
+	devices := s.config.Devices
+	if len(sc.Devices) > 0 {
+		devices = sc.Devices
+	} else if sc.DeviceCount > 0 {
+		devices = domain.DefaultFleet(sc.DeviceCount)
+	}
+
+	// Validate every load profile before anything changes; phase profiles
+	// are rebuilt when their phase starts.
+	var load *loadSchedule
+	if sc.Load != nil {
+		var err error
+		if load, err = newLoadSchedule(*sc.Load, time.Now()); err != nil {
+			return fmt.Errorf("load: %w", err)
+		}
+	}
+	if sc.Schedule != nil {
+		for i, phase := range sc.Schedule.Phases {
+			if phase.Load == nil {
+				continue
+			}
+			if _, err := newLoadSchedule(*phase.Load, time.Now()); err != nil {
+				return fmt.Errorf("schedule.phases[%d].load: %w", i, err)
+			}
+		}
+	}
+
+	var faults domain.FaultConfig
+	if sc.Faults != nil {
+		faults = *sc.Faults
+	}
+
+	unit := ""
+	if p, ok := domain.LookupSensorProfile(sensorType); ok {
+		unit = p.Unit
+	}
+	sim := newSimulation(sensorType, unit, modelCfg, faults, nil)
+	sim.bus = s.bus
+	sim.generatorID = s.sim.generatorID
+	if sc.Correlation != nil {
+		if err := sim.setCorrelation(*sc.Correlation); err != nil {
+			return fmt.Errorf("correlation: %w", err)
+		}
+	}
+	start := domain.DefaultLogicalStart
+	if sc.StartTime != nil {
+		start = *sc.StartTime
+	}
+	if err := sim.reseed(sc.Seed, start); err != nil {
+		return err
+	}
+	if err := sim.setDevices(devices); err != nil {
+		return fmt.Errorf("devices: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopScheduleLocked()
+	if s.bus != nil && s.sim.sensorType != sim.sensorType {
+		s.bus.forget(s.sim.generatorID, s.sim.sensorType)
+	}
+	s.sim = sim
+	s.scenario = sc
+	s.phase = -1
+	if sc.FrequencyMs > 0 {
+		s.UpdateFrequency(sc.FrequencyMs)
+	} else if sc.RatePerSecond > 0 {
+		s.SetRate(sc.RatePerSecond)
+	}
+	s.setLoad(load)
+	if sc.Schedule != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.scheduleCancel = cancel
+		go s.runSchedule(ctx, sc, s.currentPacing())
+	}
+	return nil
+}
+
+func (s *GeneratorService) stopScheduleLocked() {
+	if s.scheduleCancel != nil {
+		s.scheduleCancel()
+		s.scheduleCancel = nil
+	}
+}
+
+func (s *GeneratorService) stopSchedule() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopScheduleLocked()
+}
+
+func (s *GeneratorService) GetScenario() ScenarioStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := ScenarioStatus{Scenario: s.scenario}
+	if s.scenario != nil && s.scenario.Schedule != nil && s.phase >= 0 {
+		idx := s.phase
+		status.PhaseIndex = &idx
+		status.PhaseName = s.scenario.Schedule.Phases[idx].Name
+	}
+	return status
+}
+
+// runSchedule steps through the schedule phases until ctx is cancelled or
+// the last phase of a non-looping schedule has been entered. Phases that
+// set no frequency or load return to base, the pacing the scenario itself
+// set up, just as phases without faults return to the scenario's faults.
+func (s *GeneratorService) runSchedule(ctx context.Context, sc *domain.Scenario, base pacing) {
+	baseFaults := domain.FaultConfig{}
+	if sc.Faults != nil {
+		baseFaults = *sc.Faults
+	}
+
+	for {
+		for i, phase := range sc.Schedule.Phases {
+			s.mu.Lock()
+			if ctx.Err() != nil {
+				s.mu.Unlock()
+				return
+			}
+			s.phase = i
+			if phase.Load != nil {
+				load, _ := newLoadSchedule(*phase.Load, time.Now())
+				s.setLoad(load)
+			} else if phase.FrequencyMs > 0 {
+				s.UpdateFrequency(phase.FrequencyMs)
+			} else {
+				s.setPacing(base)
+			}
+			if phase.Faults != nil {
+				s.sim.setFaults(*phase.Faults)
+			} else {
+				s.sim.setFaults(baseFaults)
+			}
+			s.mu.Unlock()
+			log.Printf("scenario %q: entering phase %d %q", sc.Name, i, phase.Name)
+
+			if !sc.Schedule.Loop && i == len(sc.Schedule.Phases)-1 {
+				return
+			}
+			d := time.Duration(phase.DurationSeconds * float64(time.Second))
+			if err := sleepUntil(ctx, time.Now().Add(d)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+)
+
+func newTestGenerator(t *testing.T, frequencyMs int64) *GeneratorService {
+	t.Helper()
+	svc, err := NewGeneratorService(nil, &domain.GeneratorConfig{
+		ID:          "test",
+		SensorType:  "temperature",
+		FrequencyMs: frequencyMs,
+		Devices:     domain.DefaultFleet(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		svc.stopSchedule()
+		svc.Close()
+	})
+	return svc
+}
+
+func applyScenario(t *testing.T, svc *GeneratorService, yaml string) {
+	t.Helper()
+	sc, err := domain.ParseScenario([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ApplyScenario(sc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitPhase waits until the schedule has entered the named phase.
+func waitPhase(t *testing.T, svc *GeneratorService, name string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.GetScenario().PhaseName != name {
+		if time.Now().After(deadline) {
+			t.Fatalf("schedule did not reach phase %q", name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulePhaseWithoutPacingRevertsToScenarioFrequency(t *testing.T) {
+	svc := newTestGenerator(t, 250)
+	applyScenario(t, svc, `
+name: burst-then-steady
+frequency_ms: 1000
+schedule:
+  phases:
+    - name: burst
+      duration_seconds: 0.05
+      frequency_ms: 100
+    - name: steady
+      duration_seconds: 0.05
+`)
+	waitPhase(t, svc, "steady")
+	if got := svc.GetFrequency(); got != 1000 {
+		t.Errorf("steady phase runs every %d ms, want the scenario's 1000", got)
+	}
+}
+
+func TestSchedulePhaseWithoutPacingRevertsToScenarioRate(t *testing.T) {
+	svc := newTestGenerator(t, 250)
+	applyScenario(t, svc, `
+name: burst-then-steady
+rate_per_second: 50
+schedule:
+  phases:
+    - name: burst
+      duration_seconds: 0.05
+      frequency_ms: 100
+    - name: steady
+      duration_seconds: 0.05
+`)
+	waitPhase(t, svc, "steady")
+	if got := svc.GetRate(); got != 50 {
+		t.Errorf("steady phase targets %v readings/s, want the scenario's 50", got)
+	}
+}
+
+func TestSchedulePhaseWithoutPacingKeepsGeneratorFrequency(t *testing.T) {
+	svc := newTestGenerator(t, 250)
+	applyScenario(t, svc, `
+name: burst-then-steady
+schedule:
+  phases:
+    - name: burst
+      duration_seconds: 0.05
+      frequency_ms: 100
+    - name: steady
+      duration_seconds: 0.05
+`)
+	waitPhase(t, svc, "steady")
+	if got := svc.GetFrequency(); got != 250 {
+		t.Errorf("steady phase runs every %d ms, want the generator's 250", got)
+	}
+}
@@ -0,0 +1,713 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Reconnect delays start at reconnectBase and double up to reconnectMax.
+// A stream that stayed up for stableStream resets the backoff.
+const (
+	reconnectBase = 500 * time.Millisecond
+	reconnectMax  = 60 * time.Second
+	stableStream  = 30 * time.Second
+)
+
+// closeTimeout bounds how long a graceful close waits for outstanding acks.
+const closeTimeout = 5 * time.Second
+
+// maxInflight is how many batches may await their ack. When the window is
+// full, readings go to the disk buffer until microservice-b catches up, or
+// are dropped without one.
+const maxInflight = 64
+
+// sender delivers a generator's readings to microservice-b in batches on a
+// bidirectional Ingest stream, which acknowledges each batch by sequence
+// number.
+//
+// While the stream is up and nothing is buffered, readings are batched in
+// memory and sent directly; otherwise they are appended to the disk buffer,
+// which is drained in order once the stream reconnects. Buffered readings
+// are only removed once acknowledged, and batches sent directly that are
+// still unacknowledged when the stream breaks are buffered again, so
+// delivery is at least once. Without a buffer, readings that cannot be sent
+// are dropped.
+//
+// Requests are queued under mu and sent by a goroutine per stream without
+// holding it, since Send blocks while gRPC flow control pushes back and
+// status, counters and pausing must not wait for that.
+type sender struct {
+	id      string
+	client  pb.IngestServiceClient
+	buf     *diskBuffer
+	batch   domain.BatchConfig
+	monitor *connMonitor // optional; wakes reconnects early
+
+	mu        sync.Mutex
+	stream    pb.IngestService_IngestClient // nil while disconnected
+	direct    bool                          // readings are sent directly rather than buffered
+	pending   []*pb.Reading                 // batch being filled while sending directly
+	pendingAt time.Time                     // when the oldest pending reading arrived
+	seq       uint64                        // last sequence number used on stream
+	outbox    []*pb.IngestRequest           // queued for the send loop of stream
+	queued    chan struct{}                 // signalled when outbox grows
+	inflight  []*inflightBatch              // sent on stream, oldest first
+	drainPos  *bufferRecord                 // last buffered record sent on stream
+	breakErr  error                         // why stream broke
+	broken    chan struct{}                 // closed when stream breaks
+	acked     chan struct{}                 // signalled when the window opens up
+	backlog   chan struct{}                 // signalled when direct sends fall back to the buffer
+	dropped   uint64                        // readings lost without a buffer
+	sent      uint64                        // readings sent on stream
+	streamAck uint64                        // readings from stream acknowledged as stored
+
+	totalSent  uint64 // readings sent on all streams
+	bytesSent  uint64 // encoded size of the requests sent
+	sendErrors uint64
+	stored     uint64 // acknowledged as stored
+	rejected   uint64 // rejected by microservice-b as invalid
+	lastReject string
+	sentRate   *rateMeter
+
+	connects  uint64 // streams established, including the first
+	failures  uint64 // connect, send, ack and storage failures
+	lastError string
+	lastErrAt time.Time
+	upSince   time.Time
+	lastAck   *StreamAck
+}
+
+// inflightBatch is a batch sent on the current stream. Acknowledged batches
+// leave the window in order, committing their buffered readings.
+type inflightBatch struct {
+	seq      uint64
+	readings []*pb.Reading
+	rec      *bufferRecord // last buffered record of the batch; nil if sent directly
+	done     bool
+}
+
+// StreamAck summarizes a gracefully closed stream: the readings sent on it
+// and how many of them microservice-b acknowledged as stored. Acked falls
+// short of Sent when readings were rejected or left unacknowledged.
+type StreamAck struct {
+	Sent  uint64    `json:"sent"`
+	Acked uint64    `json:"acked"`
+	At    time.Time `json:"at"`
+}
+
+// SenderStatus describes a generator's ingest stream. While Draining, the
+// stream is up but still sending the buffer, so new readings are buffered
+// behind it.
+type SenderStatus struct {
+	Connected  bool       `json:"connected"`
+	Draining   bool       `json:"draining"`
+	Since      *time.Time `json:"connected_since,omitempty"`
+	Reconnects uint64     `json:"reconnects"`
+	Failures   uint64     `json:"failures"`
+	LastError  string     `json:"last_error,omitempty"`
+	LastErrAt  *time.Time `json:"last_error_at,omitempty"`
+	LastAck    *StreamAck `json:"last_ack,omitempty"`
+}
+
+func newSender(id string, client pb.IngestServiceClient, buf *diskBuffer, batch domain.BatchConfig) *sender {
+	if batch.Size < 1 {
+		batch.Size = 1
+	}
+	return &sender{
+		id:       id,
+		client:   client,
+		buf:      buf,
+		batch:    batch,
+		acked:    make(chan struct{}, 1),
+		backlog:  make(chan struct{}, 1),
+		sentRate: newRateMeter(),
+	}
+}
+
+// write adds msgs to the pending batch, sending every full batch, or
+// buffers them if they cannot be sent directly.
+func (s *sender) write(msgs []*pb.Reading) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.direct {
+		s.bufferLocked(msgs)
+		return
+	}
+	if len(s.pending) == 0 {
+		s.pendingAt = time.Now()
+	}
+	s.pending = append(s.pending, msgs...)
+	for len(s.pending) >= s.batch.Size {
+		if !s.sendPendingLocked(s.batch.Size) {
+			return
+		}
+	}
+	if s.batch.LingerMs == 0 && len(s.pending) > 0 {
+		s.sendPendingLocked(len(s.pending))
+	}
+}
+
+// sendPendingLocked sends the first n pending readings as one batch. When
+// the window is full, direct sends stop and the pending readings are
+// buffered instead.
+func (s *sender) sendPendingLocked(n int) bool {
+	if len(s.inflight) >= maxInflight {
+		s.direct = false
+		s.bufferLocked(s.pending)
+		s.pending = nil
+		signal(s.backlog)
+		return false
+	}
+
+	readings := s.pending[:n:n]
+	s.pending = s.pending[n:]
+	if len(s.pending) == 0 {
+		s.pending = nil
+	}
+	s.pendingAt = time.Now()
+	s.sendLocked(readings, nil)
+	return true
+}
+
+// sendLocked queues readings as the next batch for the send loop and tracks
+// it until it is acknowledged.
+func (s *sender) sendLocked(readings []*pb.Reading, rec *bufferRecord) {
+	s.seq++
+	s.inflight = append(s.inflight, &inflightBatch{seq: s.seq, readings: readings, rec: rec})
+	s.outbox = append(s.outbox, &pb.IngestRequest{Seq: s.seq, Readings: readings})
+	signal(s.queued)
+}
+
+// sendLoop sends the requests queued for stream in order. It returns when a
+// send fails, which breaks the stream, when the stream has broken, or once
+// stop is closed and nothing is left to send.
+func (s *sender) sendLoop(stream pb.IngestService_IngestClient, stop <-chan struct{}) {
+	for {
+		s.mu.Lock()
+		if s.stream != stream || s.breakErr != nil {
+			s.mu.Unlock()
+			return
+		}
+		reqs := s.outbox
+		s.outbox = nil
+		queued := s.queued
+		s.mu.Unlock()
+
+		for _, req := range reqs {
+			if err := stream.Send(req); err != nil {
+				s.mu.Lock()
+				if s.stream == stream && s.breakErr == nil {
+					s.sendErrors++
+					s.breakLocked(err)
+					log.Printf("generator %q: send error: %v", s.id, err)
+				}
+				s.mu.Unlock()
+				return
+			}
+			n := uint64(len(req.Readings))
+			s.mu.Lock()
+			s.sent += n
+			s.totalSent += n
+			s.bytesSent += uint64(proto.Size(req))
+			s.sentRate.add(n, time.Now())
+			s.mu.Unlock()
+		}
+		if len(reqs) > 0 {
+			continue
+		}
+
+		select {
+		case <-queued:
+		case <-stop:
+			s.mu.Lock()
+			idle := len(s.outbox) == 0
+			s.mu.Unlock()
+			if idle {
+				return
+			}
+		}
+	}
+}
+
+func (s *sender) bufferLocked(msgs []*pb.Reading) {
+	if len(msgs) == 0 {
+		return
+	}
+	if s.buf == nil {
+		s.dropped += uint64(len(msgs))
+		return
+	}
+	if err := s.buf.append(msgs); err != nil {
+		log.Printf("generator %q: buffer error: %v", s.id, err)
+		s.dropped += uint64(len(msgs))
+	}
+}
+
+// flushDue sends the pending batch once its oldest reading has waited for
+// the linger time.
+func (s *sender) flushDue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	linger := time.Duration(s.batch.LingerMs) * time.Millisecond
+	if s.direct && len(s.pending) > 0 && time.Since(s.pendingAt) >= linger {
+		s.sendPendingLocked(len(s.pending))
+	}
+}
+
+// breakLocked marks the current stream as broken by err. Later acks on it
+// are ignored.
+func (s *sender) breakLocked(err error) {
+	if s.breakErr != nil {
+		return
+	}
+	s.breakErr = err
+	close(s.broken)
+	s.requeueLocked()
+}
+
+// requeueLocked stops direct sends and buffers the batches sent directly
+// but not acknowledged, then the pending batch, ahead of anything written
+// afterwards. Buffered batches are still in the buffer and need nothing.
+func (s *sender) requeueLocked() {
+	var unacked []*pb.Reading
+	for _, b := range s.inflight {
+		if b.rec == nil && !b.done {
+			unacked = append(unacked, b.readings...)
+			b.done = true
+		}
+	}
+	s.bufferLocked(append(unacked, s.pending...))
+	s.pending = nil
+	s.outbox = nil
+	s.direct = false
+}
+
+// run keeps a stream open until ctx is cancelled, draining the buffer each
+// time it connects. Failures, including batches microservice-b failed to
+// store, are retried with jittered exponential backoff. Streams outlive ctx
+// so that cancelling it closes them gracefully.
+func (s *sender) run(ctx context.Context) {
+	bo := newBackoff(reconnectBase, reconnectMax)
+	for ctx.Err() == nil {
+		streamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		stream, err := s.client.Ingest(streamCtx)
+		if err != nil {
+			cancel()
+			s.fail(err)
+			delay := bo.next()
+			log.Printf("generator %q: stream error: %v, retrying in %v", s.id, err, delay.Round(time.Millisecond))
+			s.wait(ctx, delay)
+			continue
+		}
+
+		loops := s.attach(stream)
+		err = s.serve(ctx)
+		if ctx.Err() != nil {
+			s.closeGracefully(stream, loops, cancel)
+			return
+		}
+		cancel()
+		close(loops.stop)
+		<-loops.sent
+		<-loops.received
+
+		s.mu.Lock()
+		s.detachLocked()
+		up := time.Since(s.upSince)
+		s.mu.Unlock()
+		s.fail(err)
+		if up >= stableStream {
+			bo.reset()
+		}
+		delay := bo.next()
+		log.Printf("generator %q: stream closed after %v: %v, reconnecting in %v", s.id, up.Round(time.Millisecond), err, delay.Round(time.Millisecond))
+		s.wait(ctx, delay)
+	}
+}
+
+// streamLoops are the goroutines serving one stream.
+type streamLoops struct {
+	stop     chan struct{} // closed to end the send loop once it has sent everything queued
+	sent     chan struct{} // closed when the send loop has returned
+	received chan struct{} // closed once no more acks will arrive
+}
+
+// attach makes stream current and starts sending to it and receiving its
+// acks.
+func (s *sender) attach(stream pb.IngestService_IngestClient) streamLoops {
+	s.mu.Lock()
+	s.stream = stream
+	s.seq = 0
+	s.outbox = nil
+	s.queued = make(chan struct{}, 1)
+	s.sent = 0
+	s.streamAck = 0
+	s.breakErr = nil
+	s.broken = make(chan struct{})
+	s.connects++
+	s.upSince = time.Now()
+	s.mu.Unlock()
+
+	loops := streamLoops{
+		stop:     make(chan struct{}),
+		sent:     make(chan struct{}),
+		received: make(chan struct{}),
+	}
+	go func() {
+		defer close(loops.sent)
+		s.sendLoop(stream, loops.stop)
+	}()
+	go func() {
+		defer close(loops.received)
+		for {
+			ack, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF {
+					s.mu.Lock()
+					if s.stream == stream {
+						s.breakLocked(err)
+					}
+					s.mu.Unlock()
+				}
+				return
+			}
+			s.handleAck(stream, ack)
+		}
+	}()
+	return loops
+}
+
+// detachLocked forgets the current stream, buffering what it left
+// unacknowledged.
+func (s *sender) detachLocked() {
+	if s.breakErr == nil {
+		s.requeueLocked()
+	}
+	s.stream = nil
+	s.direct = false
+	s.inflight = nil
+	s.drainPos = nil
+}
+
+// handleAck settles the acknowledged batch and removes settled batches from
+// the head of the window, committing their buffered readings.
+func (s *sender) handleAck(stream pb.IngestService_IngestClient, ack *pb.IngestAck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream != stream || s.breakErr != nil {
+		return
+	}
+
+	var b *inflightBatch
+	for _, f := range s.inflight {
+		if f.seq == ack.Seq {
+			b = f
+			break
+		}
+	}
+	if b == nil || b.done {
+		return
+	}
+	switch ack.Status {
+	case pb.IngestAck_STORED:
+		s.stored += ack.Count
+		s.streamAck += ack.Count
+		s.rejected += ack.Rejected
+		if len(ack.Rejections) > 0 {
+			s.lastReject = ack.Rejections[0].Reason
+			log.Printf("generator %q: %d of %d readings in batch %d rejected, first: %s", s.id, ack.Rejected, len(b.readings), ack.Seq, s.lastReject)
+		}
+	case pb.IngestAck_REJECTED:
+		s.rejected += uint64(len(b.readings))
+		s.lastReject = ack.Reason
+		log.Printf("generator %q: batch of %d readings rejected: %s", s.id, len(b.readings), ack.Reason)
+	default:
+		// The batch is retried from the buffer after reconnecting.
+		s.breakLocked(fmt.Errorf("microservice-b failed to store batch %d: %s", ack.Seq, ack.Reason))
+		return
+	}
+	b.done = true
+
+	var commit *bufferRecord
+	for len(s.inflight) > 0 && s.inflight[0].done {
+		if rec := s.inflight[0].rec; rec != nil {
+			commit = rec
+		}
+		s.inflight = s.inflight[1:]
+	}
+	if commit != nil {
+		if err := s.buf.commit(*commit); err != nil {
+			log.Printf("generator %q: buffer error: %v", s.id, err)
+		}
+	}
+	signal(s.acked)
+}
+
+// serve drains the buffer, then sends directly until ctx is done or the
+// stream breaks, going back to draining whenever direct sends fall behind.
+func (s *sender) serve(ctx context.Context) error {
+	var flush <-chan time.Time
+	if s.batch.LingerMs > 0 {
+		ticker := time.NewTicker(time.Duration(s.batch.LingerMs) * time.Millisecond)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+	broken := s.brokenChan()
+	for {
+		if err := s.drain(ctx, broken); err != nil || ctx.Err() != nil {
+			return err
+		}
+	direct:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-broken:
+				return s.brokenErr()
+			case <-s.backlog:
+				break direct
+			case <-flush:
+				s.flushDue()
+			}
+		}
+	}
+}
+
+// drain sends buffered readings in order, within the ack window, and
+// switches to direct sends once everything buffered has been sent. New
+// readings keep going to the buffer meanwhile, so ordering is preserved. It
+// returns early when ctx is done.
+func (s *sender) drain(ctx context.Context, broken <-chan struct{}) error {
+	select {
+	case <-s.backlog:
+	default:
+	}
+	for {
+		if err := s.awaitWindow(ctx, broken); err != nil || ctx.Err() != nil {
+			return err
+		}
+
+		var records []bufferRecord
+		if s.buf != nil {
+			var err error
+			if records, err = s.buf.peekAfter(s.drainPos, s.batch.Size); err != nil {
+				return err
+			}
+		}
+
+		s.mu.Lock()
+		if s.breakErr != nil {
+			s.mu.Unlock()
+			return s.breakErr
+		}
+		if len(records) == 0 && s.buf != nil {
+			// Check again while holding the lock write buffers under.
+			var err error
+			if records, err = s.buf.peekAfter(s.drainPos, s.batch.Size); err != nil {
+				s.mu.Unlock()
+				return err
+			}
+		}
+		if len(records) == 0 {
+			s.direct = true
+			s.mu.Unlock()
+			return nil
+		}
+
+		readings := make([]*pb.Reading, len(records))
+		for i, rec := range records {
+			readings[i] = rec.reading
+		}
+		last := records[len(records)-1]
+		s.sendLocked(readings, &last)
+		s.drainPos = &last
+		s.mu.Unlock()
+	}
+}
+
+// awaitWindow waits until fewer than maxInflight batches await their ack.
+func (s *sender) awaitWindow(ctx context.Context, broken <-chan struct{}) error {
+	for {
+		s.mu.Lock()
+		full := len(s.inflight) >= maxInflight
+		s.mu.Unlock()
+		if !full {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-broken:
+			return s.brokenErr()
+		case <-s.acked:
+		}
+	}
+}
+
+func (s *sender) brokenChan() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.broken
+}
+
+func (s *sender) brokenErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.breakErr
+}
+
+func (s *sender) wait(ctx context.Context, d time.Duration) {
+	if s.monitor != nil {
+		s.monitor.waitRetry(ctx, d)
+		return
+	}
+	sleepUntil(ctx, time.Now().Add(d))
+}
+
+// closeGracefully sends the pending batch and everything queued, half-closes
+// stream and waits for the outstanding acks, cancelling the stream if this
+// takes longer than closeTimeout. Readings left unacknowledged stay
+// buffered.
+func (s *sender) closeGracefully(stream pb.IngestService_IngestClient, loops streamLoops, cancel context.CancelFunc) {
+	defer cancel()
+	s.mu.Lock()
+	if s.direct && len(s.pending) > 0 {
+		s.sendPendingLocked(len(s.pending))
+	}
+	s.direct = false
+	s.mu.Unlock()
+
+	timeout := time.NewTimer(closeTimeout)
+	defer timeout.Stop()
+	close(loops.stop)
+	select {
+	case <-loops.sent:
+		stream.CloseSend()
+		select {
+		case <-loops.received:
+		case <-timeout.C:
+			cancel()
+			<-loops.received
+		}
+	case <-timeout.C:
+		cancel()
+		<-loops.sent
+		<-loops.received
+	}
+
+	s.mu.Lock()
+	sent, acked := s.sent, s.streamAck
+	unacked := 0
+	for _, b := range s.inflight {
+		if !b.done {
+			unacked += len(b.readings)
+		}
+	}
+	err := s.breakErr
+	s.detachLocked()
+	s.lastAck = &StreamAck{Sent: sent, Acked: acked, At: time.Now()}
+	s.mu.Unlock()
+
+	if err == nil && unacked > 0 {
+		err = fmt.Errorf("%d readings unacknowledged after %v", unacked, closeTimeout)
+	}
+	if err != nil {
+		log.Printf("generator %q: closing stream: %v", s.id, err)
+		s.fail(err)
+	}
+	log.Printf("generator %q: stream closed, %d of %d readings acknowledged", s.id, acked, sent)
+}
+
+func (s *sender) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordErrorLocked(err)
+}
+
+func (s *sender) recordErrorLocked(err error) {
+	s.failures++
+	s.lastError = err.Error()
+	s.lastErrAt = time.Now()
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// counters returns the readings and bytes sent, the number of failed sends
+// and the send rate over the last RateWindow.
+func (s *sender) counters() (sent, bytes, errors uint64, rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totalSent, s.bytesSent, s.sendErrors, s.sentRate.rate(time.Now())
+}
+
+// acks returns the readings acknowledged as stored or rejected, the last
+// rejection reason and the readings awaiting an ack.
+func (s *sender) acks() (stored, rejected uint64, lastReject string, unacked int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.inflight {
+		if !b.done {
+			unacked += len(b.readings)
+		}
+	}
+	return s.stored, s.rejected, s.lastReject, unacked
+}
+
+func (s *sender) status() SenderStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connected := s.stream != nil && s.breakErr == nil
+	st := SenderStatus{
+		Connected: connected,
+		Draining:  connected && !s.direct,
+		Failures:  s.failures,
+		LastError: s.lastError,
+	}
+	if s.connects > 1 {
+		st.Reconnects = s.connects - 1
+	}
+	if connected {
+		since := s.upSince
+		st.Since = &since
+	}
+	if !s.lastErrAt.IsZero() {
+		at := s.lastErrAt
+		st.LastErrAt = &at
+	}
+	if s.lastAck != nil {
+		ack := *s.lastAck
+		st.LastAck = &ack
+	}
+	return st
+}
+
+func (s *sender) droppedCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.dropped
+	if s.buf != nil {
+		n += s.buf.droppedCount()
+	}
+	return n
+}
+
+func (s *sender) close() error {
+	if s.buf == nil {
+		return nil
+	}
+	return s.buf.close()
+}
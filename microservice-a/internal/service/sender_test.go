@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"google.golang.org/grpc"
+)
+
+// stalledClient opens Ingest streams that never acknowledge anything. While
+// block is open, Send blocks as it does under gRPC flow control.
+type stalledClient struct {
+	pb.IngestServiceClient
+	block chan struct{}
+}
+
+func (c *stalledClient) Ingest(ctx context.Context, _ ...grpc.CallOption) (grpc.BidiStreamingClient[pb.IngestRequest, pb.IngestAck], error) {
+	return &stalledStream{ctx: ctx, block: c.block, closed: make(chan struct{})}, nil
+}
+
+type stalledStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	block  chan struct{}
+	closed chan struct{}
+}
+
+func (s *stalledStream) Send(*pb.IngestRequest) error {
+	select {
+	case <-s.block:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Recv ends the stream once the client half-closes it.
+func (s *stalledStream) Recv() (*pb.IngestAck, error) {
+	select {
+	case <-s.closed:
+		return nil, io.EOF
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func (s *stalledStream) CloseSend() error {
+	close(s.closed)
+	return nil
+}
+
+func (s *stalledStream) Context() context.Context { return s.ctx }
+
+// startSender runs a sender on client until the test ends and waits for it
+// to connect and send directly.
+func startSender(t *testing.T, client pb.IngestServiceClient) *sender {
+	t.Helper()
+	s := newSender("test", client, nil, domain.BatchConfig{Size: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for st := s.status(); !st.Connected || st.Draining; st = s.status() {
+		if time.Now().After(deadline) {
+			t.Fatal("sender did not connect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return s
+}
+
+func TestSenderStaysResponsiveWhileSendBlocks(t *testing.T) {
+	client := &stalledClient{block: make(chan struct{})}
+	s := startSender(t, client)
+	t.Cleanup(func() { close(client.block) })
+
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		for i := 0; i < 3; i++ {
+			s.write(bufferReadings(i, 1))
+		}
+		s.status()
+		s.counters()
+		s.acks()
+		s.droppedCount()
+	}()
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("sender blocked behind a stalled Send")
+	}
+	if _, _, _, unacked := s.acks(); unacked != 3 {
+		t.Errorf("%d readings await an ack, want 3", unacked)
+	}
+}
+
+func TestSenderEnforcesWindowWithoutBuffer(t *testing.T) {
+	client := &stalledClient{block: make(chan struct{})}
+	close(client.block) // sends succeed, acks never come
+	s := startSender(t, client)
+
+	const batches = maxInflight + 10
+	for i := 0; i < batches; i++ {
+		s.write(bufferReadings(i, 1))
+	}
+	if _, _, _, unacked := s.acks(); unacked != maxInflight {
+		t.Errorf("%d readings await an ack, want the window of %d", unacked, maxInflight)
+	}
+	if dropped := s.droppedCount(); dropped != batches-maxInflight {
+		t.Errorf("dropped %d readings, want %d", dropped, batches-maxInflight)
+	}
+}
+
+func TestSenderReportsDrainingSeparately(t *testing.T) {
+	buf, err := openDiskBuffer(t.TempDir(), 0, domain.OverflowDropOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { buf.close() })
+	if err := buf.append(bufferReadings(0, maxInflight+10)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Acks never come, so the buffer outlasts the window and draining
+	// never ends.
+	client := &stalledClient{block: make(chan struct{})}
+	close(client.block)
+	s := newSender("test", client, buf, domain.BatchConfig{Size: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.status().Connected {
+		if time.Now().After(deadline) {
+			t.Fatal("sender did not connect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if st := s.status(); !st.Draining || st.Since == nil {
+		t.Errorf("status = %+v, want connected since the stream opened and draining", st)
+	}
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+)
+
+// deviceState is the per-device part of the simulation, so each (ID1, ID2)
+// pair forms a coherent time series.
+type deviceState struct {
+	device domain.Device
+	model  ValueModel
+	faults *faultInjector
+
+	readings uint64 // sent for this device, after faults
+	last     string // timestamp of the last reading
+}
+
+// simulation holds everything that determines a reading sequence: the
+// random source, the clock, the value model and per-device state. It is not
+// safe for concurrent use.
+type simulation struct {
+	sensorType string
+	unit       string
+
+	modelCfg    domain.ValueModelConfig
+	faultCfg    domain.FaultConfig
+	faultCounts map[domain.FaultKind]uint64
+
+	rng           *rand.Rand
+	clock         clock
+	seed          int64
+	deterministic bool
+	devices       []*deviceState
+
+	// bus shares values with other generators under generatorID; corr, if
+	// set, derives this generator's values from theirs. A nil bus disables
+	// both.
+	bus         *signalBus
+	generatorID string
+	corr        *correlator
+
+	// keyPrefix and keySeq form each reading's reading_id. The prefix is
+	// random per simulation, so a reseeded or forked sequence never
+	// collides with readings already stored.
+	keyPrefix string
+	keySeq    uint64
+}
+
+func newSimulation(sensorType, unit string, modelCfg domain.ValueModelConfig, faultCfg domain.FaultConfig, devices []domain.Device) *simulation {
+	sim := &simulation{
+		sensorType:  sensorType,
+		unit:        unit,
+		modelCfg:    modelCfg,
+		faultCfg:    faultCfg,
+		faultCounts: make(map[domain.FaultKind]uint64),
+		keyPrefix:   newKeyPrefix(),
+	}
+	for _, d := range devices {
+		sim.devices = append(sim.devices, &deviceState{device: d})
+	}
+	return sim
+}
+
+func newKeyPrefix() string {
+	b := make([]byte, 8)
+	if _, err := crand.Read(b); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// reseed restarts the sequence. With a seed, values and timestamps (from a
+// logical clock beginning at start) are reproducible. A nil seed picks a
+// time-based seed and uses wall-clock timestamps.
+func (sim *simulation) reseed(seed *int64, start time.Time) error {
+	var clk clock = wallClock{}
+	value := time.Now().UnixNano()
+	if seed != nil {
+		value = *seed
+		clk = &logicalClock{now: start.UTC()}
+	}
+
+	sim.rng = rand.New(rand.NewSource(value))
+	sim.clock = clk
+	sim.seed = value
+	sim.deterministic = seed != nil
+	return sim.resetDevices(sim.devices)
+}
+
+// resetDevices rebuilds the value model and fault state of devices from the
+// current config and random source.
+func (sim *simulation) resetDevices(devices []*deviceState) error {
+	for _, d := range devices {
+		model, err := NewValueModel(sim.modelCfg, sim.rng, sim.clock.Now())
+		if err != nil {
+			return err
+		}
+		d.model = model
+		d.faults = newFaultInjector(sim.faultCfg, sim.rng, sim.faultCounts, sim.nextKey)
+	}
+	return nil
+}
+
+// setValueModel replaces the value model; every device starts over.
+func (sim *simulation) setValueModel(cfg domain.ValueModelConfig) error {
+	if err := validateValueModel(cfg); err != nil {
+		return err
+	}
+	sim.modelCfg = cfg
+	for _, d := range sim.devices {
+		d.model, _ = NewValueModel(cfg, sim.rng, sim.clock.Now())
+	}
+	return nil
+}
+
+func (sim *simulation) setFaults(cfg domain.FaultConfig) {
+	sim.faultCfg = cfg
+	for _, d := range sim.devices {
+		d.faults.cfg = cfg
+	}
+}
+
+// setCorrelation replaces the correlation config; an empty config removes it.
+func (sim *simulation) setCorrelation(cfg domain.CorrelationConfig) error {
+	if len(cfg.Terms) == 0 && cfg.Formula == "" {
+		sim.corr = nil
+		return nil
+	}
+	corr, err := newCorrelator(cfg)
+	if err != nil {
+		return err
+	}
+	sim.corr = corr
+	return nil
+}
+
+// correlation returns the correlation config and how many of its results
+// were not finite and fell back to the model output.
+func (sim *simulation) correlation() (domain.CorrelationConfig, uint64) {
+	if sim.corr == nil {
+		return domain.CorrelationConfig{}, 0
+	}
+	return sim.corr.cfg, sim.corr.invalid
+}
+
+func (sim *simulation) faultCountsCopy() map[domain.FaultKind]uint64 {
+	counts := make(map[domain.FaultKind]uint64, len(sim.faultCounts))
+	for k, v := range sim.faultCounts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// setDevices replaces the fleet. Devices that were already present keep
+// their state; new devices start fresh.
+func (sim *simulation) setDevices(devices []domain.Device) error {
+	existing := make(map[domain.Device]*deviceState, len(sim.devices))
+	for _, d := range sim.devices {
+		existing[d.device] = d
+	}
+
+	next := make([]*deviceState, 0, len(devices))
+	var added []*deviceState
+	seen := make(map[domain.Device]bool, len(devices))
+	for _, d := range devices {
+		if seen[d] {
+			return fmt.Errorf("duplicate device %s", d)
+		}
+		seen[d] = true
+		if state, ok := existing[d]; ok {
+			next = append(next, state)
+			continue
+		}
+		state := &deviceState{device: d}
+		next = append(next, state)
+		added = append(added, state)
+	}
+
+	if err := sim.resetDevices(added); err != nil {
+		return err
+	}
+	sim.devices = next
+	return nil
+}
+
+func (sim *simulation) deviceList() []domain.Device {
+	devices := make([]domain.Device, len(sim.devices))
+	for i, d := range sim.devices {
+		devices[i] = d.device
+	}
+	return devices
+}
+
+// deviceStats returns the per-device reading counters.
+func (sim *simulation) deviceStats() []DeviceStats {
+	stats := make([]DeviceStats, len(sim.devices))
+	for i, d := range sim.devices {
+		stats[i] = DeviceStats{
+			ID1:           d.device.ID1,
+			ID2:           d.device.ID2,
+			Readings:      d.readings,
+			LastTimestamp: d.last,
+		}
+	}
+	return stats
+}
+
+// value returns the next value of device: the model output, correlated with
+// other generators when configured, and published for them before faults.
+func (sim *simulation) value(d *deviceState, now time.Time) float64 {
+	v := d.model.Next(now)
+	if sim.bus == nil {
+		return v
+	}
+	if sim.corr != nil {
+		v = clamp(sim.corr.apply(sim.bus, d.device, v), sim.modelCfg)
+	}
+	sim.bus.publish(sim.generatorID, sim.sensorType, d.device, v)
+	return v
+}
+
+// nextKey returns a reading_id not used before in this simulation.
+func (sim *simulation) nextKey() string {
+	sim.keySeq++
+	return fmt.Sprintf("%s-%d", sim.keyPrefix, sim.keySeq)
+}
+
+// tick produces one reading per device at the current clock time, passes
+// each through fault injection (which may drop, duplicate or reorder it)
+// and advances the clock by interval. Each reading gets a unique
+// reading_id before faults; injected duplicates get their own unless the
+// fault config re-uses the original's.
+func (sim *simulation) tick(interval time.Duration) []*pb.Reading {
+	now := sim.clock.Now()
+	var out []*pb.Reading
+	for _, d := range sim.devices {
+		msg := &pb.Reading{
+			ReadingId:  sim.nextKey(),
+			Value:      sim.value(d, now),
+			SensorType: sim.sensorType,
+			Unit:       sim.unit,
+			Id1:        d.device.ID1,
+			Id2:        int32(d.device.ID2),
+			Timestamp:  now.Format(time.RFC3339Nano),
+		}
+		n := len(out)
+		out = append(out, d.faults.Apply(msg)...)
+		if len(out) > n {
+			d.readings += uint64(len(out) - n)
+			d.last = msg.Timestamp
+		}
+	}
+	sim.clock.Advance(interval)
+	return out
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+)
+
+var simStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestSimulation(t *testing.T, seed int64, faults domain.FaultConfig) *simulation {
+	t.Helper()
+	model := domain.ValueModelConfig{Type: domain.ValueModelGaussian, Baseline: 20, Noise: 2}
+	sim := newSimulation("temperature", "°C", model, faults, domain.DefaultFleet(3))
+	if err := sim.reseed(&seed, simStart); err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+func TestDuplicateFaultUsesFreshKey(t *testing.T) {
+	sim := newTestSimulation(t, 1, domain.FaultConfig{DuplicateProbability: 1})
+	out := sim.tick(time.Second)
+	if len(out) != 6 {
+		t.Fatalf("tick sent %d readings, want every one of 3 twice", len(out))
+	}
+	keys := make(map[string]bool)
+	for _, r := range out {
+		keys[r.ReadingId] = true
+	}
+	if len(keys) != 6 {
+		t.Errorf("%d distinct reading_ids among 6 readings; duplicates would be stored once", len(keys))
+	}
+	for i := 0; i < len(out); i += 2 {
+		orig, dup := out[i], out[i+1]
+		if orig.Id1 != dup.Id1 || orig.Id2 != dup.Id2 || orig.Value != dup.Value || orig.Timestamp != dup.Timestamp {
+			t.Errorf("duplicate %v differs from %v", dup, orig)
+		}
+	}
+}
+
+func TestDuplicateFaultCanReuseKey(t *testing.T) {
+	sim := newTestSimulation(t, 1, domain.FaultConfig{DuplicateProbability: 1, DuplicateReuseKey: true})
+	out := sim.tick(time.Second)
+	if len(out) != 6 {
+		t.Fatalf("tick sent %d readings, want every one of 3 twice", len(out))
+	}
+	for i := 0; i < len(out); i += 2 {
+		if out[i].ReadingId != out[i+1].ReadingId {
+			t.Errorf("duplicate key %q, want the original's %q", out[i+1].ReadingId, out[i].ReadingId)
+		}
+	}
+}
+
+// seededRun reseeds svc and returns what it sends over ticks ticks, without
+// the reading_ids, which are unique per run by design.
+func seededRun(t *testing.T, svc *GeneratorService, seed int64, ticks int) []*pb.Reading {
+	t.Helper()
+	if err := svc.SetSeed(&seed, simStart); err != nil {
+		t.Fatal(err)
+	}
+	var out []*pb.Reading
+	for i := 0; i < ticks; i++ {
+		readings, _ := svc.nextReadings()
+		for _, r := range readings {
+			out = append(out, &pb.Reading{
+				Id1: r.Id1, Id2: r.Id2, SensorType: r.SensorType,
+				Value: r.Value, Unit: r.Unit, Timestamp: r.Timestamp,
+			})
+		}
+	}
+	return out
+}
+
+func TestSeedReproducesReadings(t *testing.T) {
+	faults := domain.FaultConfig{
+		DropProbability:        0.1,
+		SpikeProbability:       0.1,
+		SpikeMagnitude:         50,
+		MalformedIDProbability: 0.1,
+		SkewProbability:        0.1,
+		MaxSkewSeconds:         30,
+		OutOfOrderProbability:  0.1,
+	}
+	runs := make([][]*pb.Reading, 2)
+	for i := range runs {
+		svc := newTestGenerator(t, 250)
+		svc.UpdateFaults(faults)
+		runs[i] = seededRun(t, svc, 42, 50)
+	}
+	if len(runs[0]) == 0 || !reflect.DeepEqual(runs[0], runs[1]) {
+		t.Fatalf("two runs with seed 42 differ:\n%v\n%v", runs[0], runs[1])
+	}
+	if got, want := runs[0][0].Timestamp, "2024-01-01T00:00:00Z"; got != want {
+		t.Errorf("first timestamp %s, want the start time %s", got, want)
+	}
+
+	svc := newTestGenerator(t, 250)
+	svc.UpdateFaults(faults)
+	if reflect.DeepEqual(seededRun(t, svc, 43, 50), runs[0]) {
+		t.Error("seeds 42 and 43 produced the same readings")
+	}
+	if again := seededRun(t, svc, 42, 50); !reflect.DeepEqual(again, runs[0]) {
+		t.Error("reseeding with 42 did not restart the sequence")
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GeneratorStats reports how closely a generator keeps to its target rate
+// and what it has delivered to microservice-b. Achieved rates are measured
+// over the last RateWindow.
+type GeneratorStats struct {
+	ID            string         `json:"id"`
+	State         GeneratorState `json:"state"`
+	StartedAt     time.Time      `json:"started_at"`
+	UptimeSeconds float64        `json:"uptime_seconds"`
+	IntervalUs    float64        `json:"interval_us"`
+
+	TargetRate       float64 `json:"target_rate"`   // readings per second
+	AchievedRate     float64 `json:"achieved_rate"` // readings sent per second, after faults
+	TargetTickRate   float64 `json:"target_tick_rate"`
+	AchievedTickRate float64 `json:"achieved_tick_rate"`
+
+	Ticks        uint64 `json:"ticks"`
+	SkippedTicks uint64 `json:"skipped_ticks"` // dropped after falling behind schedule
+	Readings     uint64 `json:"readings"`      // generated, whether sent directly or buffered
+
+	// Sent counts readings written to the stream, including drained and
+	// resent ones. Microservice-b acknowledges each batch as stored or
+	// rejected; Unacked readings are still awaiting their ack. Stream.LastAck
+	// summarizes the last gracefully closed stream.
+	Sent          uint64  `json:"sent"`
+	SentBytes     uint64  `json:"sent_bytes"` // protobuf-encoded size of the requests
+	SentRate      float64 `json:"sent_rate"`  // readings sent per second
+	SendErrors    uint64  `json:"send_errors"`
+	Stored        uint64  `json:"stored"`
+	Rejected      uint64  `json:"rejected"`
+	LastRejection string  `json:"last_rejection,omitempty"`
+	Unacked       int     `json:"unacked"`
+
+	// Stream.Connected is false while microservice-b is unreachable;
+	// Stream.Draining is true while a live stream catches up on the buffer.
+	Stream        SenderStatus `json:"stream"`
+	Buffered      int64        `json:"buffered"`
+	BufferedBytes int64        `json:"buffered_bytes"`
+	Dropped       uint64       `json:"dropped"` // lost to buffer overflow, or unsent without a buffer
+
+	Devices []DeviceStats `json:"devices"`
+}
+
+// DeviceStats counts the readings generated for one device, after faults.
+type DeviceStats struct {
+	ID1           string `json:"id1"`
+	ID2           int    `json:"id2"`
+	Readings      uint64 `json:"readings"`
+	LastTimestamp string `json:"last_timestamp,omitempty"`
+}
+
+// RateWindow is the period achieved rates are averaged over.
+const RateWindow = 10 * time.Second
+
+// generatorStats collects emission counters for one generator.
+type generatorStats struct {
+	ticks    uint64
+	skipped  uint64
+	readings uint64
+
+	tickRate    *rateMeter
+	readingRate *rateMeter
+}
+
+func newGeneratorStats() *generatorStats {
+	return &generatorStats{tickRate: newRateMeter(), readingRate: newRateMeter()}
+}
+
+func (st *generatorStats) tick(readings int, now time.Time) {
+	atomic.AddUint64(&st.ticks, 1)
+	atomic.AddUint64(&st.readings, uint64(readings))
+	st.tickRate.add(1, now)
+	st.readingRate.add(uint64(readings), now)
+}
+
+func (st *generatorStats) skip(ticks uint64) {
+	atomic.AddUint64(&st.skipped, ticks)
+}
+
+// rateMeter estimates a rate from periodic samples of a running total.
+type rateMeter struct {
+	mu      sync.Mutex
+	total   uint64
+	samples []rateSample // oldest first, spaced at least rateSampleEvery apart
+}
+
+type rateSample struct {
+	at    time.Time
+	total uint64
+}
+
+const rateSampleEvery = RateWindow / 40
+
+func newRateMeter() *rateMeter {
+	return &rateMeter{}
+}
+
+func (m *rateMeter) add(n uint64, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) == 0 || now.Sub(m.samples[len(m.samples)-1].at) >= rateSampleEvery {
+		m.samples = append(m.samples, rateSample{at: now, total: m.total})
+		for len(m.samples) > 1 && now.Sub(m.samples[1].at) >= RateWindow {
+			m.samples = m.samples[1:]
+		}
+	}
+	m.total += n
+}
+
+// rate returns the average rate per second since the oldest sample in the
+// window, so it decays towards 0 when nothing is added.
+func (m *rateMeter) rate(now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) == 0 {
+		return 0
+	}
+	oldest := m.samples[0]
+	elapsed := now.Sub(oldest.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(m.total-oldest.total) / elapsed
+}
+
+// Stats returns the generator's emission counters and rates.
+func (s *GeneratorService) Stats() GeneratorStats {
+	interval := s.CurrentInterval()
+	s.mu.Lock()
+	devices := s.sim.deviceStats()
+	s.mu.Unlock()
+
+	now := time.Now()
+	state, _ := s.control.status()
+	stats := GeneratorStats{
+		ID:               s.config.ID,
+		State:            state,
+		StartedAt:        s.startedAt,
+		UptimeSeconds:    now.Sub(s.startedAt).Seconds(),
+		IntervalUs:       float64(interval) / float64(time.Microsecond),
+		AchievedRate:     s.stats.readingRate.rate(now),
+		AchievedTickRate: s.stats.tickRate.rate(now),
+		Ticks:            atomic.LoadUint64(&s.stats.ticks),
+		SkippedTicks:     atomic.LoadUint64(&s.stats.skipped),
+		Readings:         atomic.LoadUint64(&s.stats.readings),
+		Stream:           s.out.status(),
+		Dropped:          s.out.droppedCount(),
+		Devices:          devices,
+	}
+	stats.Sent, stats.SentBytes, stats.SendErrors, stats.SentRate = s.out.counters()
+	stats.Stored, stats.Rejected, stats.LastRejection, stats.Unacked = s.out.acks()
+	if s.out.buf != nil {
+		stats.Buffered, stats.BufferedBytes = s.out.buf.len()
+	}
+	if interval > 0 {
+		stats.TargetTickRate = float64(time.Second) / float64(interval)
+		stats.TargetRate = stats.TargetTickRate * float64(len(devices))
+	}
+	return stats
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-a/internal/domain"
+)
+
+// ValueModel produces successive sensor values. Implementations may keep
+// state between calls and are not safe for concurrent use.
+type ValueModel interface {
+	Next(t time.Time) float64
+}
+
+// NewValueModel builds the ValueModel described by cfg. start anchors the
+// time-dependent models (sine, drift).
+func NewValueModel(cfg domain.ValueModelConfig, rng *rand.Rand, start time.Time) (ValueModel, error) {
+	if cfg.Min != nil && cfg.Max != nil && *cfg.Min > *cfg.Max {
+		return nil, fmt.Errorf("min %v is greater than max %v", *cfg.Min, *cfg.Max)
+	}
+
+	switch cfg.Type {
+	case domain.ValueModelUniform:
+		if cfg.Min == nil || cfg.Max == nil {
+			return nil, fmt.Errorf("uniform model requires min and max")
+		}
+		return &uniformModel{cfg: cfg, rng: rng}, nil
+	case domain.ValueModelSine:
+		if cfg.PeriodSeconds <= 0 {
+			return nil, fmt.Errorf("sine model requires a positive period_seconds")
+		}
+		return &sineModel{cfg: cfg, rng: rng, start: start}, nil
+	case domain.ValueModelGaussian:
+		return &gaussianModel{cfg: cfg, rng: rng}, nil
+	case domain.ValueModelRandomWalk:
+		return &randomWalkModel{cfg: cfg, rng: rng, current: cfg.Baseline}, nil
+	case domain.ValueModelDrift:
+		return &driftModel{cfg: cfg, rng: rng, start: start}, nil
+	case domain.ValueModelStep:
+		return &stepModel{cfg: cfg, rng: rng, level: cfg.Baseline}, nil
+	default:
+		return nil, fmt.Errorf("unknown value model type %q", cfg.Type)
+	}
+}
+
+// validateValueModel reports whether cfg describes a usable model.
+func validateValueModel(cfg domain.ValueModelConfig) error {
+	_, err := NewValueModel(cfg, nil, time.Time{})
+	return err
+}
+
+func clamp(v float64, cfg domain.ValueModelConfig) float64 {
+	if cfg.Min != nil && v < *cfg.Min {
+		v = *cfg.Min
+	}
+	if cfg.Max != nil && v > *cfg.Max {
+		v = *cfg.Max
+	}
+	return v
+}
+
+func noise(rng *rand.Rand, stddev float64) float64 {
+	if stddev == 0 {
+		return 0
+	}
+	return rng.NormFloat64() * stddev
+}
+
+type uniformModel struct {
+	cfg domain.ValueModelConfig
+	rng *rand.Rand
+}
+
+func (m *uniformModel) Next(time.Time) float64 {
+	return *m.cfg.Min + m.rng.Float64()*(*m.cfg.Max-*m.cfg.Min)
+}
+
+type sineModel struct {
+	cfg   domain.ValueModelConfig
+	rng   *rand.Rand
+	start time.Time
+}
+
+func (m *sineModel) Next(t time.Time) float64 {
+	phase := 2 * math.Pi * t.Sub(m.start).Seconds() / m.cfg.PeriodSeconds
+	v := m.cfg.Baseline + m.cfg.Amplitude*math.Sin(phase) + noise(m.rng, m.cfg.Noise)
+	return clamp(v, m.cfg)
+}
+
+type gaussianModel struct {
+	cfg domain.ValueModelConfig
+	rng *rand.Rand
+}
+
+func (m *gaussianModel) Next(time.Time) float64 {
+	return clamp(m.cfg.Baseline+noise(m.rng, m.cfg.Noise), m.cfg)
+}
+
+// randomWalkModel reflects off Min/Max so the walk does not stick to a bound.
+type randomWalkModel struct {
+	cfg     domain.ValueModelConfig
+	rng     *rand.Rand
+	current float64
+}
+
+func (m *randomWalkModel) Next(time.Time) float64 {
+	v := m.current + noise(m.rng, m.cfg.Noise)
+	if m.cfg.Min != nil && v < *m.cfg.Min {
+		v = 2**m.cfg.Min - v
+	}
+	if m.cfg.Max != nil && v > *m.cfg.Max {
+		v = 2**m.cfg.Max - v
+	}
+	m.current = clamp(v, m.cfg)
+	return m.current
+}
+
+type driftModel struct {
+	cfg   domain.ValueModelConfig
+	rng   *rand.Rand
+	start time.Time
+}
+
+func (m *driftModel) Next(t time.Time) float64 {
+	v := m.cfg.Baseline + m.cfg.DriftPerSecond*t.Sub(m.start).Seconds() + noise(m.rng, m.cfg.Noise)
+	return clamp(v, m.cfg)
+}
+
+type stepModel struct {
+	cfg   domain.ValueModelConfig
+	rng   *rand.Rand
+	level float64
+}
+
+func (m *stepModel) Next(time.Time) float64 {
+	if m.rng.Float64() < m.cfg.StepProbability {
+		m.level = clamp(m.level+(m.rng.Float64()*2-1)*m.cfg.StepSize, m.cfg)
+	}
+	return clamp(m.level+noise(m.rng, m.cfg.Noise), m.cfg)
+}
@@ -23,6 +23,7 @@ import (
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -32,6 +33,7 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 
 	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
 	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/handler"
@@ -62,6 +64,13 @@ func main() {
 		jwtSecret = "your-secret-key"
 	}
 
+	// gRPC readings are written by an ingest queue; see service.IngestQueue.
+	queueConfig := service.DefaultIngestQueueConfig
+	queueConfig.Capacity = envInt("INGEST_QUEUE_SIZE", queueConfig.Capacity)
+	queueConfig.Workers = envInt("INGEST_WORKERS", queueConfig.Workers)
+	queueConfig.FlushSize = envInt("INGEST_FLUSH_SIZE", queueConfig.FlushSize)
+	queueConfig.FlushInterval = time.Duration(envInt("INGEST_FLUSH_INTERVAL_MS", int(queueConfig.FlushInterval/time.Millisecond))) * time.Millisecond
+
 	// Connect to PostgreSQL
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -88,7 +97,8 @@ func main() {
 	sensorService := service.NewSensorService(repo)
 	sensorHandler := handler.NewSensorHandler(sensorService)
 	authHandler := handler.NewAuthHandler()
-	grpcHandler := handler.NewGRPCHandler(sensorService)
+	ingestQueue := service.NewIngestQueue(sensorService, queueConfig)
+	grpcHandler := handler.NewGRPCHandler(ingestQueue)
 
 	// Start gRPC server
 	go func() {
@@ -96,7 +106,11 @@ func main() {
 		if err != nil {
 			log.Fatal("failed to listen:", err)
 		}
-		grpcServer := grpc.NewServer()
+		// Allow the generators' keepalive pings, which detect dead streams.
+		grpcServer := grpc.NewServer(grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}))
 		pb.RegisterIngestServiceServer(grpcServer, grpcHandler)
 		log.Printf("Microservice B gRPC server listening on :%s", grpcPort)
 		if err := grpcServer.Serve(lis); err != nil {
@@ -114,7 +128,10 @@ func main() {
 	// Public routes
 	e.POST("/api/auth/login", authHandler.Login)
 	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(200, map[string]string{"status": "healthy"})
+		return c.JSON(200, map[string]interface{}{
+			"status":       "healthy",
+			"ingest_queue": ingestQueue.Stats(),
+		})
 	})
 	
 	// Swagger documentation
@@ -129,8 +146,11 @@ func main() {
 
 	// Sensor readings endpoints
 	api.GET("/readings", sensorHandler.GetReadings)
+	api.GET("/readings/export", sensorHandler.ExportReadings)
+	api.GET("/readings/aggregate", sensorHandler.AggregateReadings)
 	api.GET("/readings/:id", sensorHandler.GetReadingByID)
 	api.POST("/readings", sensorHandler.CreateReading, customMiddleware.RequireRole("admin"))
+	api.POST("/readings/bulk", sensorHandler.CreateReadings, customMiddleware.RequireRole("admin"))
 	api.PUT("/readings/:id", sensorHandler.UpdateReading, customMiddleware.RequireRole("admin"))
 	api.DELETE("/readings", sensorHandler.DeleteReadings, customMiddleware.RequireRole("admin"))
 
@@ -138,6 +158,20 @@ func main() {
 	e.Logger.Fatal(e.Start(":" + httpPort))
 }
 
+// envInt returns the positive integer in the environment variable name, or
+// def when it is unset.
+func envInt(name string, def int) int {
+	str := os.Getenv(name)
+	if str == "" {
+		return def
+	}
+	v, err := strconv.Atoi(str)
+	if err != nil || v <= 0 {
+		log.Fatalf("invalid %s: %q", name, str)
+	}
+	return v
+}
+
 func runMigrations(db *sql.DB) error {
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
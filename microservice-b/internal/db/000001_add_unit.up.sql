@@ -0,0 +1 @@
+ALTER TABLE sensor_readings ADD COLUMN IF NOT EXISTS unit VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sensor_reading_id;
+ALTER TABLE sensor_readings DROP COLUMN IF EXISTS reading_id;
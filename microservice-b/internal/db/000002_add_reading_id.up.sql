@@ -0,0 +1,2 @@
+ALTER TABLE sensor_readings ADD COLUMN IF NOT EXISTS reading_id VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sensor_reading_id ON sensor_readings (reading_id);
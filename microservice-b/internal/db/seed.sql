@@ -4,7 +4,10 @@ CREATE TABLE IF NOT EXISTS sensor_readings (
     id2 INT NOT NULL,
     sensor_type VARCHAR(50) NOT NULL,
     value DOUBLE PRECISION NOT NULL,
-    ts TIMESTAMP WITH TIME ZONE NOT NULL
+    unit VARCHAR(20) NOT NULL DEFAULT '',
+    ts TIMESTAMP WITH TIME ZONE NOT NULL,
+    reading_id VARCHAR(64)
 );
 
 CREATE INDEX idx_sensor_id1_id2_ts ON sensor_readings (id1, id2, ts);
+CREATE UNIQUE INDEX idx_sensor_reading_id ON sensor_readings (reading_id);
@@ -2,12 +2,26 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"github.com/glitchdawg/synthetic_sensors/shared/domain"
 )
 
+// ErrInvalidReading wraps database errors caused by the data itself, such as
+// constraint violations, which retrying cannot fix.
+var ErrInvalidReading = errors.New("invalid reading")
+
+// ErrInvalidAggregate marks aggregate queries that cannot be answered as
+// asked, such as ones producing too many buckets.
+var ErrInvalidAggregate = errors.New("invalid aggregate query")
+
 type SensorReadingRepository interface {
 	Create(ctx context.Context, reading *domain.SensorReading) error
+	CreateBatch(ctx context.Context, readings []domain.SensorReading) error
 	GetByFilter(ctx context.Context, filter *domain.SensorReadingFilter) (*domain.PaginatedResponse, error)
+	// Export calls fn for each reading matching filter, oldest first,
+	// without loading them all into memory.
+	Export(ctx context.Context, filter *domain.SensorReadingFilter, fn func(*domain.SensorReading) error) error
+	Aggregate(ctx context.Context, query *domain.AggregateQuery) ([]domain.AggregateBucket, error)
 	Update(ctx context.Context, id int, reading *domain.SensorReading) error
 	Delete(ctx context.Context, filter *domain.SensorReadingFilter) (int64, error)
 	GetByID(ctx context.Context, id int) (*domain.SensorReading, error)
@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/service"
+	"github.com/glitchdawg/synthetic_sensors/shared/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// bulkBatchSize is how many valid rows are stored per COPY.
+const bulkBatchSize = 5000
+
+// maxReportedErrors bounds the row errors listed in a BulkImportReport.
+const maxReportedErrors = 1000
+
+// BulkImportReport summarizes a bulk import. Rows are numbered from 1,
+// not counting a CSV header.
+type BulkImportReport struct {
+	Rows     int            `json:"rows" example:"1000"`
+	Stored   int            `json:"stored" example:"998"` // includes readings skipped because their reading_id was already stored
+	Rejected int            `json:"rejected" example:"2"`
+	Errors   []BulkRowError `json:"errors,omitempty"`           // the first rejected rows
+	Error    string         `json:"error,omitempty" example:""` // why the import stopped early, if it did
+}
+
+type BulkRowError struct {
+	Row   int    `json:"row" example:"17"`
+	Error string `json:"error" example:"invalid value \"abc\""`
+}
+
+func (r *BulkImportReport) reject(row int, err error) {
+	r.Rejected++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, BulkRowError{Row: row, Error: err.Error()})
+	}
+}
+
+// rowError is a problem with a single row; the import continues after it.
+type rowError struct{ err error }
+
+func (e *rowError) Error() string { return e.err.Error() }
+
+// rowSource yields the rows of an upload one at a time, returning io.EOF
+// after the last. A *rowError rejects the row; any other error ends the
+// import.
+type rowSource interface {
+	next() (domain.SensorReading, error)
+}
+
+//	@Summary		Import sensor readings in bulk
+//	@Description	Import readings from a JSON array, NDJSON (one reading per line) or CSV (header row with id1, id2, sensor_type, value, timestamp and optionally unit and reading_id) body, or from a multipart "file" upload (requires admin privileges).
+//	@Description	The format comes from the format parameter, the Content-Type or the uploaded file's extension. Rows are validated one by one and valid rows are stored in batches; the report lists the rejected rows.
+//	@Description	Readings whose reading_id is already stored are skipped.
+//	@Tags			Sensor Readings
+//	@Accept			json,mpfd,text/csv,application/x-ndjson
+//	@Produce		json
+//	@Param			format	query		string					false	"Body format"	Enums(json, ndjson, csv)
+//	@Param			file	formData	file					false	"File to import"
+//	@Success		200		{object}	BulkImportReport		"Import report"
+//	@Failure		400		{object}	BulkImportReport		"Malformed body; rows before the error were imported"
+//	@Failure		401		{object}	map[string]string		"Unauthorized"
+//	@Failure		403		{object}	map[string]string		"Forbidden - Admin access required"
+//	@Failure		415		{object}	map[string]string		"Unsupported format"
+//	@Failure		500		{object}	BulkImportReport		"Internal server error; rows before the error were imported"
+//	@Security		Bearer
+//	@Router			/api/readings/bulk [post]
+func (h *SensorHandler) CreateReadings(c echo.Context) error {
+	body, format, err := bulkBody(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, BulkImportReport{Error: err.Error()})
+	}
+	defer body.Close()
+
+	var src rowSource
+	switch format {
+	case "json":
+		src, err = newJSONRows(body)
+	case "ndjson":
+		src = newNDJSONRows(body)
+	case "csv":
+		src, err = newCSVRows(body)
+	default:
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": "unsupported format, use json, ndjson or csv"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, BulkImportReport{Error: err.Error()})
+	}
+
+	report, err := h.importRows(c.Request().Context(), src)
+	switch {
+	case errors.Is(err, errStorage):
+		report.Error = err.Error()
+		return c.JSON(http.StatusInternalServerError, report)
+	case err != nil:
+		report.Error = err.Error()
+		return c.JSON(http.StatusBadRequest, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// errStorage marks import errors caused by the database rather than the
+// upload.
+var errStorage = errors.New("storing readings failed")
+
+// importRows validates the rows of src and stores the valid ones in
+// batches of bulkBatchSize.
+func (h *SensorHandler) importRows(ctx context.Context, src rowSource) (BulkImportReport, error) {
+	var report BulkImportReport
+	batch := make([]domain.SensorReading, 0, bulkBatchSize)
+	var rows []int
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch, rows = batch[:0], rows[:0] }()
+
+		err := h.service.CreateReadings(ctx, batch)
+		if err == nil {
+			report.Stored += len(batch)
+			return nil
+		}
+		if !errors.Is(err, service.ErrInvalidReading) {
+			return fmt.Errorf("%w: %v", errStorage, err)
+		}
+		// The database refused some row; store the batch row by row to
+		// find out which.
+		for i := range batch {
+			switch err := h.service.CreateReading(ctx, &batch[i]); {
+			case err == nil:
+				report.Stored++
+			case errors.Is(err, service.ErrInvalidReading):
+				report.reject(rows[i], err)
+			default:
+				return fmt.Errorf("%w: %v", errStorage, err)
+			}
+		}
+		return nil
+	}
+
+	for row := 1; ; row++ {
+		reading, err := src.next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *rowError
+		if err != nil && !errors.As(err, &rowErr) {
+			if ferr := flush(); ferr != nil {
+				return report, ferr
+			}
+			return report, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		report.Rows++
+		if err == nil {
+			err = validateReading(h.validator, &reading)
+		}
+		if err != nil {
+			report.reject(row, err)
+			continue
+		}
+
+		batch = append(batch, reading)
+		rows = append(rows, row)
+		if len(batch) == bulkBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, flush()
+}
+
+// bulkBody returns the upload and its format: the format parameter if set,
+// otherwise derived from the Content-Type or, for multipart uploads, from
+// the "file" part.
+func bulkBody(c echo.Context) (io.ReadCloser, string, error) {
+	format := c.QueryParam("format")
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		if format == "" {
+			format = formatOf(mediaType, "")
+		}
+		return c.Request().Body, format, nil
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", fmt.Errorf("missing file upload: %v", err)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	if format == "" {
+		format = formatOf(fh.Header.Get(echo.HeaderContentType), fh.Filename)
+	}
+	return f, format, nil
+}
+
+func formatOf(contentType, filename string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		return "json"
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return "ndjson"
+	case "text/csv":
+		return "csv"
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".csv":
+		return "csv"
+	}
+	return ""
+}
+
+// jsonRows decodes a JSON array element by element.
+type jsonRows struct {
+	dec *json.Decoder
+}
+
+func newJSONRows(r io.Reader) (*jsonRows, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("body must be a JSON array of readings")
+	}
+	return &jsonRows{dec: dec}, nil
+}
+
+func (s *jsonRows) next() (domain.SensorReading, error) {
+	var reading domain.SensorReading
+	if !s.dec.More() {
+		if _, err := s.dec.Token(); err != nil {
+			return reading, err
+		}
+		return reading, io.EOF
+	}
+	err := s.dec.Decode(&reading)
+	// A value of the wrong type is skipped whole; syntax errors are fatal.
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return reading, &rowError{err}
+	}
+	return reading, err
+}
+
+// ndjsonRows decodes one reading per line; blank lines are skipped.
+type ndjsonRows struct {
+	r *bufio.Reader
+}
+
+func newNDJSONRows(r io.Reader) *ndjsonRows {
+	return &ndjsonRows{r: bufio.NewReader(r)}
+}
+
+func (s *ndjsonRows) next() (domain.SensorReading, error) {
+	var reading domain.SensorReading
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return reading, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(line), &reading); err != nil {
+			return reading, &rowError{err}
+		}
+		return reading, nil
+	}
+}
+
+// csvRows parses CSV with a header row naming the columns.
+type csvRows struct {
+	r    *csv.Reader
+	cols map[string]int
+}
+
+func newCSVRows(r io.Reader) (*csvRows, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %v", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "ts" {
+			name = "timestamp"
+		}
+		cols[name] = i
+	}
+	for _, name := range []string{"id1", "id2", "sensor_type", "value", "timestamp"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("CSV header is missing column %q", name)
+		}
+	}
+	return &csvRows{r: cr, cols: cols}, nil
+}
+
+func (s *csvRows) next() (domain.SensorReading, error) {
+	var reading domain.SensorReading
+	record, err := s.r.Read()
+	if err == io.EOF {
+		return reading, err
+	}
+	if err != nil {
+		// The reader resumes at the next record after a malformed one.
+		return reading, &rowError{err}
+	}
+
+	field := func(name string) string {
+		if i, ok := s.cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	reading.ID1 = field("id1")
+	reading.SensorType = field("sensor_type")
+	reading.Unit = field("unit")
+	if id := field("reading_id"); id != "" {
+		reading.ReadingID = &id
+	}
+	if reading.ID2, err = strconv.Atoi(field("id2")); err != nil {
+		return reading, &rowError{fmt.Errorf("invalid id2 %q", field("id2"))}
+	}
+	if reading.Value, err = strconv.ParseFloat(field("value"), 64); err != nil {
+		return reading, &rowError{fmt.Errorf("invalid value %q", field("value"))}
+	}
+	if reading.Timestamp, err = time.Parse(time.RFC3339Nano, field("timestamp")); err != nil {
+		return reading, &rowError{fmt.Errorf("invalid timestamp %q", field("timestamp"))}
+	}
+	return reading, nil
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/service"
+	"github.com/glitchdawg/synthetic_sensors/shared/domain"
+)
+
+func TestCSVRows(t *testing.T) {
+	body := `ID1, id2 ,sensor_type,value,ts,unit,reading_id
+A,0,light,0,2024-01-15T10:30:00Z,lx,
+B,999,temperature,-3.25,2024-01-15T10:30:00.5+01:00,°C,r-1
+C,x,temperature,1,2024-01-15T10:30:00Z,,
+C,1,temperature,abc,2024-01-15T10:30:00Z,,
+C,1,temperature,1,yesterday,,
+"C,1,temperature,1,2024-01-15T10:30:00Z,,
+`
+	rows, err := newCSVRows(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		reading domain.SensorReading
+		err     string
+	}{
+		{reading: domain.SensorReading{ID1: "A", ID2: 0, SensorType: "light", Value: 0, Unit: "lx", Timestamp: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)}},
+		{reading: domain.SensorReading{ID1: "B", ID2: 999, SensorType: "temperature", Value: -3.25, Unit: "°C", Timestamp: time.Date(2024, 1, 15, 9, 30, 0, 5e8, time.UTC)}},
+		{err: `invalid id2 "x"`},
+		{err: `invalid value "abc"`},
+		{err: `invalid timestamp "yesterday"`},
+		{err: "extraneous or missing \" in quoted-field"},
+	}
+	for i, w := range want {
+		got, err := rows.next()
+		if w.err != "" {
+			var rowErr *rowError
+			if !errors.As(err, &rowErr) || !strings.Contains(err.Error(), w.err) {
+				t.Errorf("row %d: error %v, want a row error containing %q", i+1, err, w.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("row %d: %v", i+1, err)
+			continue
+		}
+		if got.ID1 != w.reading.ID1 || got.ID2 != w.reading.ID2 || got.SensorType != w.reading.SensorType ||
+			got.Value != w.reading.Value || got.Unit != w.reading.Unit || !got.Timestamp.Equal(w.reading.Timestamp) {
+			t.Errorf("row %d = %+v, want %+v", i+1, got, w.reading)
+		}
+	}
+	if _, err := rows.next(); err != io.EOF {
+		t.Errorf("after the last row: %v, want io.EOF", err)
+	}
+}
+
+func TestCSVRowsReadingID(t *testing.T) {
+	rows, err := newCSVRows(strings.NewReader("id1,id2,sensor_type,value,timestamp,reading_id\nA,1,co2,400,2024-01-15T10:30:00Z,r-7\nA,1,co2,400,2024-01-15T10:30:00Z,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := rows.next()
+	if err != nil || first.ReadingID == nil || *first.ReadingID != "r-7" {
+		t.Errorf("first row reading_id = %v, %v, want r-7", first.ReadingID, err)
+	}
+	second, err := rows.next()
+	if err != nil || second.ReadingID != nil {
+		t.Errorf("second row reading_id = %v, %v, want none", second.ReadingID, err)
+	}
+}
+
+func TestCSVRowsMissingColumn(t *testing.T) {
+	for _, header := range []string{
+		"id1,id2,sensor_type,value",
+		"id1,sensor_type,value,timestamp",
+		"",
+	} {
+		if _, err := newCSVRows(strings.NewReader(header + "\n")); err == nil {
+			t.Errorf("header %q accepted", header)
+		}
+	}
+}
+
+func TestImportRejectsNonFiniteValues(t *testing.T) {
+	repo := &memoryRepository{}
+	h := NewSensorHandler(service.NewSensorService(repo))
+
+	body := `id1,id2,sensor_type,value,timestamp
+A,1,temperature,NaN,2024-01-15T10:30:00Z
+A,1,temperature,Inf,2024-01-15T10:30:00Z
+A,1,temperature,-Inf,2024-01-15T10:30:00Z
+A,1,temperature,+infinity,2024-01-15T10:30:00Z
+A,1,temperature,1e400,2024-01-15T10:30:00Z
+A,1,temperature,21.5,2024-01-15T10:30:00Z
+`
+	src, err := newCSVRows(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := h.importRows(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 6 || report.Stored != 1 || report.Rejected != 5 {
+		t.Fatalf("report = %+v, want 6 rows with 1 stored and 5 rejected", report)
+	}
+	for i, e := range report.Errors {
+		if e.Row != i+1 {
+			t.Errorf("error %d is for row %d, want %d", i, e.Row, i+1)
+		}
+	}
+	if len(repo.readings) != 1 || repo.readings[0].Value != 21.5 {
+		t.Errorf("stored %+v", repo.readings)
+	}
+}
+
+func TestImportNDJSON(t *testing.T) {
+	repo := &memoryRepository{}
+	h := NewSensorHandler(service.NewSensorService(repo))
+
+	body := `{"id1":"A","id2":0,"sensor_type":"vibration","value":0,"timestamp":"2024-01-15T10:30:00Z"}
+
+{"id1":"A","id2":"x","sensor_type":"vibration","value":1,"timestamp":"2024-01-15T10:30:00Z"}
+{"id1":"A","id2":1,"sensor_type":"vibration","value":1e400,"timestamp":"2024-01-15T10:30:00Z"}
+{"id1":"A","id2":1000,"sensor_type":"vibration","value":1,"timestamp":"2024-01-15T10:30:00Z"}
+`
+	report, err := h.importRows(context.Background(), newNDJSONRows(strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 4 || report.Stored != 1 || report.Rejected != 3 {
+		t.Fatalf("report = %+v, want 4 rows with 1 stored and 3 rejected", report)
+	}
+}
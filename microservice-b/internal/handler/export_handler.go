@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/parquet"
+	"github.com/glitchdawg/synthetic_sensors/shared/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// exportFormat is an export encoding and how it is announced.
+type exportFormat struct {
+	contentType string
+	ext         string
+	newWriter   func(w io.Writer) (exportWriter, error)
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":     {"text/csv", "csv", newCSVExport},
+	"ndjson":  {"application/x-ndjson", "ndjson", newNDJSONExport},
+	"parquet": {"application/vnd.apache.parquet", "parquet", newParquetExport},
+}
+
+// exportWriter encodes readings in one export format.
+type exportWriter interface {
+	write(r *domain.SensorReading) error
+	close() error
+}
+
+//	@Summary		Export sensor readings
+//	@Description	Stream all readings matching the filters, oldest first, as CSV, NDJSON or Parquet. The format comes from the format parameter or the Accept header (text/csv, application/x-ndjson, application/vnd.apache.parquet) and defaults to CSV.
+//	@Description	Rows are read through a database cursor and written as they arrive, so exports of any size use constant memory. CSV exports can be imported again with POST /api/readings/bulk.
+//	@Tags			Sensor Readings
+//	@Produce		text/csv,application/x-ndjson,application/vnd.apache.parquet
+//	@Param			id1		query		string	false	"Filter by ID1 (A-Z)"
+//	@Param			id2		query		int		false	"Filter by ID2 (0-999)"
+//	@Param			from	query		string	false	"Start timestamp (RFC3339 format)"
+//	@Param			to		query		string	false	"End timestamp (RFC3339 format)"
+//	@Param			format	query		string	false	"Export format"	Enums(csv, ndjson, parquet)
+//	@Success		200		{file}		file	"Readings in the requested format"
+//	@Failure		400		{object}	map[string]string	"Invalid request parameters"
+//	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Failure		406		{object}	map[string]string	"Unsupported format"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Security		Bearer
+//	@Router			/api/readings/export [get]
+func (h *SensorHandler) ExportReadings(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	name := c.QueryParam("format")
+	if name == "" {
+		name = acceptedFormat(c.Request().Header.Get(echo.HeaderAccept))
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		return c.JSON(http.StatusNotAcceptable, map[string]string{"error": "unsupported format, use csv, ndjson or parquet"})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.contentType)
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="readings.`+format.ext+`"`)
+
+	// Nothing reaches the client until the buffer first fills, so errors
+	// before then can still be reported properly.
+	buf := bufio.NewWriterSize(res, 64*1024)
+	w, err := format.newWriter(buf)
+	if err == nil {
+		err = h.service.ExportReadings(c.Request().Context(), filter, w.write)
+	}
+	if err == nil {
+		err = w.close()
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		if !res.Committed {
+			res.Header().Del(echo.HeaderContentType)
+			res.Header().Del(echo.HeaderContentDisposition)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		// The status is already sent; cutting the stream short is all
+		// that is left.
+		log.Printf("export failed after %d bytes: %v", res.Size, err)
+	}
+	return nil
+}
+
+// acceptedFormat picks the first export format named in an Accept header.
+// A missing header or */* selects CSV.
+func acceptedFormat(accept string) string {
+	if accept == "" {
+		return "csv"
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv", "*/*", "text/*":
+			return "csv"
+		case "application/x-ndjson", "application/ndjson", "application/jsonl":
+			return "ndjson"
+		case "application/vnd.apache.parquet", "application/parquet", "application/x-parquet":
+			return "parquet"
+		}
+	}
+	return ""
+}
+
+// csvExport writes the columns that POST /api/readings/bulk reads.
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w io.Writer) (exportWriter, error) {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"id", "id1", "id2", "sensor_type", "value", "unit", "timestamp", "reading_id"})
+	return &csvExport{w: cw}, err
+}
+
+func (e *csvExport) write(r *domain.SensorReading) error {
+	readingID := ""
+	if r.ReadingID != nil {
+		readingID = *r.ReadingID
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(r.ID),
+		r.ID1,
+		strconv.Itoa(r.ID2),
+		r.SensorType,
+		strconv.FormatFloat(r.Value, 'g', -1, 64),
+		r.Unit,
+		r.Timestamp.UTC().Format(time.RFC3339Nano),
+		readingID,
+	})
+}
+
+func (e *csvExport) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonExport writes one reading per line, as the REST API returns them.
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func newNDJSONExport(w io.Writer) (exportWriter, error) {
+	return &ndjsonExport{enc: json.NewEncoder(w)}, nil
+}
+
+func (e *ndjsonExport) write(r *domain.SensorReading) error { return e.enc.Encode(r) }
+
+func (e *ndjsonExport) close() error { return nil }
+
+var parquetColumns = []parquet.Column{
+	{Name: "id", Type: parquet.Int64},
+	{Name: "id1", Type: parquet.ByteArray, Annotation: parquet.String},
+	{Name: "id2", Type: parquet.Int32},
+	{Name: "sensor_type", Type: parquet.ByteArray, Annotation: parquet.String},
+	{Name: "value", Type: parquet.Double},
+	{Name: "unit", Type: parquet.ByteArray, Annotation: parquet.String},
+	{Name: "timestamp", Type: parquet.Int64, Annotation: parquet.TimestampMicros},
+	{Name: "reading_id", Type: parquet.ByteArray, Annotation: parquet.String, Optional: true},
+}
+
+type parquetExport struct {
+	w *parquet.Writer
+}
+
+func newParquetExport(w io.Writer) (exportWriter, error) {
+	pw, err := parquet.NewWriter(w, parquetColumns, parquet.DefaultRowGroupSize)
+	return &parquetExport{w: pw}, err
+}
+
+func (e *parquetExport) write(r *domain.SensorReading) error {
+	var readingID interface{}
+	if r.ReadingID != nil {
+		readingID = *r.ReadingID
+	}
+	return e.w.Write(r.ID, r.ID1, r.ID2, r.SensorType, r.Value, r.Unit, r.Timestamp, readingID)
+}
+
+func (e *parquetExport) close() error { return e.w.Close() }
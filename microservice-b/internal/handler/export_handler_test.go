@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/service"
+	"github.com/glitchdawg/synthetic_sensors/shared/domain"
+	"github.com/labstack/echo/v4"
+)
+
+func export(t *testing.T, repo *memoryRepository, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	h := NewSensorHandler(service.NewSensorService(repo))
+	req := httptest.NewRequest(http.MethodGet, "/api/readings/export?"+query, nil)
+	rec := httptest.NewRecorder()
+	if err := h.ExportReadings(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestExportEmptyParquet(t *testing.T) {
+	rec := export(t, &memoryRepository{}, "format=parquet")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); ct != "application/vnd.apache.parquet" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	data := rec.Body.Bytes()
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("not a Parquet file: %q", data)
+	}
+	if footer := int(binary.LittleEndian.Uint32(data[len(data)-8:])); footer != len(data)-12 {
+		t.Errorf("footer of %d bytes in a file of %d bytes, want only the footer between the magic", footer, len(data))
+	}
+}
+
+func TestExportCSVImportsAgain(t *testing.T) {
+	readingID := "r-1"
+	repo := &memoryRepository{readings: []domain.SensorReading{
+		{ID: 1, ID1: "A", ID2: 0, SensorType: "light", Value: 0, Unit: "lx", Timestamp: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), ReadingID: &readingID},
+		{ID: 2, ID1: "B", ID2: 999, SensorType: "temperature", Value: -3.25, Timestamp: time.Date(2024, 1, 15, 10, 30, 0, 500, time.UTC)},
+	}}
+	rec := export(t, repo, "format=csv")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	rows, err := newCSVRows(strings.NewReader(rec.Body.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range repo.readings {
+		got, err := rows.next()
+		if err != nil {
+			t.Fatalf("row %d: %v", i+1, err)
+		}
+		if got.ID1 != want.ID1 || got.ID2 != want.ID2 || got.SensorType != want.SensorType || got.Value != want.Value ||
+			got.Unit != want.Unit || !got.Timestamp.Equal(want.Timestamp) || (got.ReadingID == nil) != (want.ReadingID == nil) {
+			t.Errorf("row %d = %+v, want %+v", i+1, got, want)
+		}
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("id,id1,id2,")) {
+		t.Errorf("unexpected header: %q", rec.Body.String())
+	}
+}
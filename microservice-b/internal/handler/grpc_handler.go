@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
 	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/service"
 	"github.com/glitchdawg/synthetic_sensors/shared/domain"
@@ -12,50 +18,249 @@ import (
 
 type GRPCHandler struct {
 	pb.UnimplementedIngestServiceServer
-	service *service.SensorService
+	queue     *service.IngestQueue
+	validator *validator.Validate
 }
 
-func NewGRPCHandler(service *service.SensorService) *GRPCHandler {
+func NewGRPCHandler(queue *service.IngestQueue) *GRPCHandler {
 	return &GRPCHandler{
-		service: service,
+		queue:     queue,
+		validator: validator.New(),
 	}
 }
 
+// Write queues readings one at a time and reports how many were stored
+// once the client closes the stream. Readings that fail validation are
+// rejected and reported in the WriteAck.
 func (h *GRPCHandler) Write(stream pb.IngestService_WriteServer) error {
-	count := uint64(0)
+	var writes streamWrites
+	var rejected rejections
 	
-	for {
+	for index := uint64(0); ; index++ {
 		reading, err := stream.Recv()
 		if err == io.EOF {
-			return stream.SendAndClose(&pb.WriteAck{Count: count})
+			return stream.SendAndClose(rejected.writeAck(writes.wait()))
 		}
 		if err != nil {
 			log.Printf("stream receive error: %v", err)
 			return err
 		}
 
-		// Parse timestamp
-		ts, err := time.Parse(time.RFC3339Nano, reading.Timestamp)
+		// Convert to domain model
+		sensorReading, err := h.toSensorReading(reading)
 		if err != nil {
-			log.Printf("timestamp parse error: %v", err)
-			ts = time.Now().UTC()
+			rejected.add(index, reading, err)
+			continue
 		}
 
-		// Convert to domain model
-		sensorReading := &domain.SensorReading{
-			ID1:        reading.Id1,
-			ID2:        int(reading.Id2),
-			SensorType: reading.SensorType,
-			Value:      reading.Value,
-			Timestamp:  ts,
+		// Queue for the database; blocks while the queue is full
+		if err := writes.enqueue(stream.Context(), h.queue, []domain.SensorReading{sensorReading}); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteBatch queues the valid readings of each received batch, which the
+// ingest queue stores together with other batches. A batch that fails to
+// store is skipped as a whole.
+func (h *GRPCHandler) WriteBatch(stream pb.IngestService_WriteBatchServer) error {
+	var writes streamWrites
+	var rejected rejections
+	index := uint64(0)
+
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(rejected.writeAck(writes.wait()))
+		}
+		if err != nil {
+			log.Printf("stream receive error: %v", err)
+			return err
+		}
+
+		readings := h.validReadings(batch.Readings, index, &rejected)
+		index += uint64(len(batch.Readings))
+		if len(readings) == 0 {
+			continue
+		}
+
+		if err := writes.enqueue(stream.Context(), h.queue, readings); err != nil {
+			return err
+		}
+	}
+}
+
+// streamWrites tracks the queued writes of a Write or WriteBatch stream.
+type streamWrites struct {
+	pending sync.WaitGroup
+	stored  atomic.Uint64
+}
+
+func (w *streamWrites) enqueue(ctx context.Context, queue *service.IngestQueue, readings []domain.SensorReading) error {
+	w.pending.Add(1)
+	err := queue.Enqueue(ctx, readings, func(err error) {
+		defer w.pending.Done()
+		if err != nil {
+			log.Printf("failed to save %d readings: %v", len(readings), err)
+			return
+		}
+		w.stored.Add(uint64(len(readings)))
+	})
+	if err != nil {
+		w.pending.Done()
+	}
+	return err
+}
+
+// wait returns the number of readings stored once all queued writes are
+// done.
+func (w *streamWrites) wait() uint64 {
+	w.pending.Wait()
+	return w.stored.Load()
+}
+
+// maxPendingAcks is how many Ingest requests may await their ack before
+// the stream stops reading.
+const maxPendingAcks = 64
+
+// pendingAck is an Ingest ack waiting for its request to be stored. result
+// is nil when the ack is already decided.
+type pendingAck struct {
+	ack    *pb.IngestAck
+	count  int
+	result chan error
+}
+
+// Ingest queues the valid readings of each request and acknowledges the
+// request by sequence number once they are stored: STORED, REJECTED when no
+// reading is valid or the database refuses the data, or FAILED when storing
+// may succeed on retry. A STORED ack lists the readings that failed
+// validation. Requests are stored concurrently, but acks are sent in
+// request order.
+func (h *GRPCHandler) Ingest(stream pb.IngestService_IngestServer) error {
+	acks := make(chan pendingAck, maxPendingAcks)
+	sent := make(chan error, 1)
+	go func() {
+		var sendErr error
+		for p := range acks {
+			if p.result != nil {
+				if err := <-p.result; err != nil {
+					log.Printf("failed to save batch %d of %d readings: %v", p.ack.Seq, p.count, err)
+					p.ack.Status = pb.IngestAck_FAILED
+					if errors.Is(err, service.ErrInvalidReading) {
+						p.ack.Status = pb.IngestAck_REJECTED
+					}
+					p.ack.Reason = err.Error()
+				} else {
+					p.ack.Count = uint64(p.count)
+				}
+			}
+			// Keep draining after a failed send so the receive loop never
+			// blocks on a full channel.
+			if sendErr == nil {
+				sendErr = stream.Send(p.ack)
+			}
+		}
+		sent <- sendErr
+	}()
+
+	// The handler must not return while the goroutine may still send.
+	finish := func(err error) error {
+		close(acks)
+		if sendErr := <-sent; err == nil {
+			err = sendErr
+		}
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return finish(nil)
+		}
+		if err != nil {
+			log.Printf("stream receive error: %v", err)
+			return finish(err)
+		}
+
+		var rejected rejections
+		readings := h.validReadings(req.Readings, 0, &rejected)
+
+		p := pendingAck{
+			ack:   &pb.IngestAck{Seq: req.Seq, Rejected: rejected.count, Rejections: rejected.list},
+			count: len(readings),
 		}
+		if len(readings) == 0 && rejected.count > 0 {
+			p.ack.Status = pb.IngestAck_REJECTED
+			p.ack.Reason = fmt.Sprintf("all %d readings failed validation, first: %s", rejected.count, rejected.list[0].Reason)
+		} else {
+			result := make(chan error, 1)
+			if err := h.queue.Enqueue(stream.Context(), readings, func(err error) { result <- err }); err != nil {
+				return finish(err)
+			}
+			p.result = result
+		}
+		acks <- p
+	}
+}
 
-		// Save to database
-		if err := h.service.CreateReading(stream.Context(), sensorReading); err != nil {
-			log.Printf("failed to save reading: %v", err)
+// validReadings converts readings, adding those that fail validation to
+// rejected. first is the index of readings[0] in the stream.
+func (h *GRPCHandler) validReadings(readings []*pb.Reading, first uint64, rejected *rejections) []domain.SensorReading {
+	valid := make([]domain.SensorReading, 0, len(readings))
+	for i, reading := range readings {
+		r, err := h.toSensorReading(reading)
+		if err != nil {
+			rejected.add(first+uint64(i), reading, err)
 			continue
 		}
+		valid = append(valid, r)
+	}
+	return valid
+}
 
-		count++
+// toSensorReading converts reading and applies the same validation as the
+// REST API. Timestamps must be RFC 3339; nothing is substituted.
+func (h *GRPCHandler) toSensorReading(reading *pb.Reading) (domain.SensorReading, error) {
+	ts, err := time.Parse(time.RFC3339Nano, reading.Timestamp)
+	if err != nil {
+		return domain.SensorReading{}, fmt.Errorf("invalid timestamp %q", reading.Timestamp)
+	}
+	r := domain.SensorReading{
+		ID1:        reading.Id1,
+		ID2:        int(reading.Id2),
+		SensorType: reading.SensorType,
+		Value:      reading.Value,
+		Unit:       reading.Unit,
+		Timestamp:  ts,
+	}
+	if reading.ReadingId != "" {
+		r.ReadingID = &reading.ReadingId
+	}
+	if err := validateReading(h.validator, &r); err != nil {
+		return domain.SensorReading{}, err
 	}
+	return r, nil
+}
+
+// maxRejections bounds the rejections listed in one ack.
+const maxRejections = 100
+
+// rejections collects the readings that failed validation. All of them are
+// logged and counted; the first maxRejections are reported to the client.
+type rejections struct {
+	count uint64
+	list  []*pb.Rejection
+}
+
+func (r *rejections) add(index uint64, reading *pb.Reading, err error) {
+	log.Printf("rejected reading %d (%s:%d, reading_id %q): %v", index, reading.Id1, reading.Id2, reading.ReadingId, err)
+	r.count++
+	if len(r.list) < maxRejections {
+		r.list = append(r.list, &pb.Rejection{Index: index, ReadingId: reading.ReadingId, Reason: err.Error()})
+	}
+}
+
+func (r *rejections) writeAck(count uint64) *pb.WriteAck {
+	return &pb.WriteAck{Count: count, Rejected: r.count, Rejections: r.list}
 }
\ No newline at end of file
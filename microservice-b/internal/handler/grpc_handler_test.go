@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/service"
+	pb "github.com/glitchdawg/synthetic_sensors/proto/ingestpb"
+	"github.com/glitchdawg/synthetic_sensors/shared/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// memoryRepository stores readings in memory.
+type memoryRepository struct {
+	mu       sync.Mutex
+	readings []domain.SensorReading
+}
+
+func (r *memoryRepository) Create(ctx context.Context, reading *domain.SensorReading) error {
+	return r.CreateBatch(ctx, []domain.SensorReading{*reading})
+}
+
+func (r *memoryRepository) CreateBatch(ctx context.Context, readings []domain.SensorReading) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readings = append(r.readings, readings...)
+	return nil
+}
+
+func (r *memoryRepository) GetByFilter(ctx context.Context, filter *domain.SensorReadingFilter) (*domain.PaginatedResponse, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) Export(ctx context.Context, filter *domain.SensorReadingFilter, fn func(*domain.SensorReading) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.readings {
+		if err := fn(&r.readings[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) Aggregate(ctx context.Context, query *domain.AggregateQuery) ([]domain.AggregateBucket, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, id int, reading *domain.SensorReading) error {
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, filter *domain.SensorReadingFilter) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryRepository) GetByID(ctx context.Context, id int) (*domain.SensorReading, error) {
+	return nil, nil
+}
+
+// dialIngest serves a GRPCHandler backed by repo over an in-memory
+// connection.
+func dialIngest(t *testing.T, repo *memoryRepository) pb.IngestServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	queue := service.NewIngestQueue(service.NewSensorService(repo), service.IngestQueueConfig{})
+	pb.RegisterIngestServiceServer(srv, NewGRPCHandler(queue))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewIngestServiceClient(conn)
+}
+
+func TestIngestAcceptsZeroValues(t *testing.T) {
+	repo := &memoryRepository{}
+	client := dialIngest(t, repo)
+
+	stream, err := client.Ingest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &pb.IngestRequest{Seq: 1, Readings: []*pb.Reading{
+		{Id1: "A", Id2: 7, SensorType: "light", Value: 0, Unit: "lx", Timestamp: "2024-01-15T10:30:00Z"},
+		{Id1: "A", Id2: 0, SensorType: "temperature", Value: 21.5, Unit: "°C", Timestamp: "2024-01-15T10:30:00Z"},
+	}}
+	if err := stream.Send(req); err != nil {
+		t.Fatal(err)
+	}
+	ack, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+
+	if ack.Status != pb.IngestAck_STORED || ack.Count != 2 || ack.Rejected != 0 {
+		t.Fatalf("ack = %v, want STORED with 2 readings; rejections: %v", ack, ack.Rejections)
+	}
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if len(repo.readings) != 2 {
+		t.Fatalf("stored %d readings, want 2", len(repo.readings))
+	}
+	if repo.readings[0].Value != 0 || repo.readings[1].ID2 != 0 {
+		t.Errorf("stored %+v", repo.readings)
+	}
+}
+
+func TestWriteRejectsInvalidReadings(t *testing.T) {
+	repo := &memoryRepository{}
+	client := dialIngest(t, repo)
+
+	stream, err := client.Write(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	readings := []*pb.Reading{
+		{Id1: "A", Id2: 0, SensorType: "vibration", Value: 0, Timestamp: "2024-01-15T10:30:00Z"},
+		{Id1: "A", Id2: 1000, SensorType: "vibration", Value: 1, Timestamp: "2024-01-15T10:30:00Z"},
+		{Id1: "a", Id2: 1, SensorType: "vibration", Value: 1, Timestamp: "2024-01-15T10:30:00Z"},
+	}
+	for _, r := range readings {
+		if err := stream.Send(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ack, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Count != 1 || ack.Rejected != 2 {
+		t.Fatalf("ack = %v, want 1 stored and 2 rejected", ack)
+	}
+	if ack.Rejections[0].Index != 1 || ack.Rejections[1].Index != 2 {
+		t.Errorf("rejections = %v, want indexes 1 and 2", ack.Rejections)
+	}
+}
+
+func TestIngestRejectsNonFiniteValues(t *testing.T) {
+	repo := &memoryRepository{}
+	client := dialIngest(t, repo)
+
+	stream, err := client.Ingest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &pb.IngestRequest{Seq: 1, Readings: []*pb.Reading{
+		{Id1: "A", Id2: 1, SensorType: "temperature", Value: math.NaN(), Timestamp: "2024-01-15T10:30:00Z"},
+		{Id1: "A", Id2: 1, SensorType: "temperature", Value: math.Inf(-1), Timestamp: "2024-01-15T10:30:00Z"},
+	}}
+	if err := stream.Send(req); err != nil {
+		t.Fatal(err)
+	}
+	ack, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+
+	if ack.Status != pb.IngestAck_REJECTED || ack.Rejected != 2 {
+		t.Fatalf("ack = %v, want REJECTED with 2 rejections", ack)
+	}
+	if !strings.Contains(ack.Rejections[0].Reason, "not a finite number") {
+		t.Errorf("rejection reason %q", ack.Rejections[0].Reason)
+	}
+}
+
+func TestIngestRejectsOverlongFieldsIndividually(t *testing.T) {
+	repo := &memoryRepository{}
+	client := dialIngest(t, repo)
+
+	stream, err := client.Ingest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &pb.IngestRequest{Seq: 1, Readings: []*pb.Reading{
+		{Id1: "A", Id2: 1, SensorType: "temperature", Value: 20, Timestamp: "2024-01-15T10:30:00Z"},
+		{Id1: "ABCDEFGHIJK", Id2: 1, SensorType: "temperature", Value: 20, Timestamp: "2024-01-15T10:30:00Z"},
+		{Id1: "A", Id2: 1, SensorType: strings.Repeat("x", 51), Value: 20, Timestamp: "2024-01-15T10:30:00Z"},
+	}}
+	if err := stream.Send(req); err != nil {
+		t.Fatal(err)
+	}
+	ack, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+
+	if ack.Status != pb.IngestAck_STORED || ack.Count != 1 || ack.Rejected != 2 {
+		t.Fatalf("ack = %v, want STORED with 1 reading and 2 rejections", ack)
+	}
+	if ack.Rejections[0].Index != 1 || ack.Rejections[1].Index != 2 {
+		t.Errorf("rejections = %v, want indexes 1 and 2", ack.Rejections)
+	}
+}
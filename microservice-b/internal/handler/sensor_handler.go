@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -23,6 +27,15 @@ func NewSensorHandler(service *service.SensorService) *SensorHandler {
 	}
 }
 
+// validateReading applies the validation every ingestion path shares: the
+// struct tags, plus a finite value, which the tags cannot express.
+func validateReading(v *validator.Validate, reading *domain.SensorReading) error {
+	if math.IsNaN(reading.Value) || math.IsInf(reading.Value, 0) {
+		return fmt.Errorf("value %v is not a finite number", reading.Value)
+	}
+	return v.Struct(reading)
+}
+
 //	@Summary		Get sensor readings
 //	@Description	Get sensor readings with optional filtering by ID1, ID2, time range, and pagination
 //	@Tags			Sensor Readings
@@ -41,31 +54,9 @@ func NewSensorHandler(service *service.SensorService) *SensorHandler {
 //	@Security		Bearer
 //	@Router			/api/readings [get]
 func (h *SensorHandler) GetReadings(c echo.Context) error {
-	filter := &domain.SensorReadingFilter{}
-
-	if id1 := c.QueryParam("id1"); id1 != "" {
-		filter.ID1 = &id1
-	}
-	if id2Str := c.QueryParam("id2"); id2Str != "" {
-		id2, err := strconv.Atoi(id2Str)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id2 format"})
-		}
-		filter.ID2 = &id2
-	}
-	if fromStr := c.QueryParam("from"); fromStr != "" {
-		from, err := time.Parse(time.RFC3339, fromStr)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid from date format"})
-		}
-		filter.From = &from
-	}
-	if toStr := c.QueryParam("to"); toStr != "" {
-		to, err := time.Parse(time.RFC3339, toStr)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid to date format"})
-		}
-		filter.To = &to
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	page, _ := strconv.Atoi(c.QueryParam("page"))
@@ -91,6 +82,93 @@ func (h *SensorHandler) GetReadings(c echo.Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+//	@Summary		Aggregate sensor readings
+//	@Description	Bucket the readings matching the filters by time, and optionally by id1, id2 and sensor_type, and compute aggregate functions over each bucket. Buckets are aligned to midnight UTC; at most 10000 are returned.
+//	@Tags			Sensor Readings
+//	@Accept			json
+//	@Produce		json
+//	@Param			bucket		query		string	false	"Bucket size (default: 1h)"	Enums(1m, 5m, 1h, 1d)
+//	@Param			fn			query		string	false	"Comma-separated functions: avg, min, max, sum, count, stddev, first, last, or a percentile such as p50, p95 or p99.9 (default: avg)"
+//	@Param			group_by	query		string	false	"Comma-separated columns to group by: id1, id2, sensor_type"
+//	@Param			id1			query		string	false	"Filter by ID1 (A-Z)"
+//	@Param			id2			query		int		false	"Filter by ID2 (0-999)"
+//	@Param			from		query		string	false	"Start timestamp (RFC3339 format)"
+//	@Param			to			query		string	false	"End timestamp (RFC3339 format)"
+//	@Success		200			{object}	domain.AggregateResult	"Aggregated readings"
+//	@Failure		400			{object}	map[string]string		"Invalid request parameters"
+//	@Failure		401			{object}	map[string]string		"Unauthorized"
+//	@Failure		500			{object}	map[string]string		"Internal server error"
+//	@Security		Bearer
+//	@Router			/api/readings/aggregate [get]
+func (h *SensorHandler) AggregateReadings(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	query := &domain.AggregateQuery{
+		Filter:    *filter,
+		Bucket:    c.QueryParam("bucket"),
+		Functions: splitList(c.QueryParam("fn")),
+		GroupBy:   splitList(c.QueryParam("group_by")),
+	}
+	if query.Bucket == "" {
+		query.Bucket = "1h"
+	}
+
+	result, err := h.service.AggregateReadings(c.Request().Context(), query)
+	if errors.Is(err, service.ErrInvalidAggregate) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// splitList splits a comma-separated query parameter, dropping empty items.
+func splitList(param string) []string {
+	var items []string
+	for _, item := range strings.Split(param, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseFilter reads the id1, id2, from and to query parameters.
+func parseFilter(c echo.Context) (*domain.SensorReadingFilter, error) {
+	filter := &domain.SensorReadingFilter{}
+
+	if id1 := c.QueryParam("id1"); id1 != "" {
+		filter.ID1 = &id1
+	}
+	if id2Str := c.QueryParam("id2"); id2Str != "" {
+		id2, err := strconv.Atoi(id2Str)
+		if err != nil {
+			return nil, errors.New("invalid id2 format")
+		}
+		filter.ID2 = &id2
+	}
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return nil, errors.New("invalid from date format")
+		}
+		filter.From = &from
+	}
+	if toStr := c.QueryParam("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return nil, errors.New("invalid to date format")
+		}
+		filter.To = &to
+	}
+	return filter, nil
+}
+
 //	@Summary		Get sensor reading by ID
 //	@Description	Get a specific sensor reading by its ID
 //	@Tags			Sensor Readings
@@ -140,7 +218,7 @@ func (h *SensorHandler) CreateReading(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
 	}
 
-	if err := h.validator.Struct(reading); err != nil {
+	if err := validateReading(h.validator, reading); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
@@ -177,7 +255,7 @@ func (h *SensorHandler) UpdateReading(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
 	}
 
-	if err := h.validator.Struct(reading); err != nil {
+	if err := validateReading(h.validator, reading); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
@@ -205,31 +283,9 @@ func (h *SensorHandler) UpdateReading(c echo.Context) error {
 //	@Security		Bearer
 //	@Router			/api/readings [delete]
 func (h *SensorHandler) DeleteReadings(c echo.Context) error {
-	filter := &domain.SensorReadingFilter{}
-
-	if id1 := c.QueryParam("id1"); id1 != "" {
-		filter.ID1 = &id1
-	}
-	if id2Str := c.QueryParam("id2"); id2Str != "" {
-		id2, err := strconv.Atoi(id2Str)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id2 format"})
-		}
-		filter.ID2 = &id2
-	}
-	if fromStr := c.QueryParam("from"); fromStr != "" {
-		from, err := time.Parse(time.RFC3339, fromStr)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid from date format"})
-		}
-		filter.From = &from
-	}
-	if toStr := c.QueryParam("to"); toStr != "" {
-		to, err := time.Parse(time.RFC3339, toStr)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid to date format"})
-		}
-		filter.To = &to
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	count, err := h.service.DeleteReadings(c.Request().Context(), filter)
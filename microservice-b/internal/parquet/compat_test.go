@@ -0,0 +1,195 @@
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	parquetgo "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// The tests in this file read the writer's output with parquet-go, a
+// Parquet implementation independent of this package, so they catch
+// mistakes that the decoder in parquet_test.go would share with the writer.
+
+// openReference opens data with parquet-go and checks its schema against
+// columns.
+func openReference(t *testing.T, data []byte, columns []Column) *parquetgo.File {
+	t.Helper()
+	f, err := parquetgo.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("parquet-go cannot open the file: %v", err)
+	}
+
+	fields := f.Schema().Fields()
+	if len(fields) != len(columns) {
+		t.Fatalf("parquet-go sees %d columns, want %d", len(fields), len(columns))
+	}
+	elements := f.Metadata().Schema[1:]
+	for i, col := range columns {
+		field, el := fields[i], elements[i]
+		if field.Name() != col.Name || !field.Leaf() || field.Optional() != col.Optional || field.Required() == col.Optional {
+			t.Errorf("column %d: parquet-go sees %s (leaf %v, optional %v), want %+v", i, field.Name(), field.Leaf(), field.Optional(), col)
+		}
+		if el.Type == nil || int32(*el.Type) != int32(col.Type) {
+			t.Errorf("column %s: physical type %v, want %d", col.Name, el.Type, col.Type)
+		}
+		var want *deprecated.ConvertedType
+		switch col.Annotation {
+		case String:
+			c := deprecated.UTF8
+			want = &c
+		case TimestampMicros:
+			c := deprecated.TimestampMicros
+			want = &c
+		}
+		if (el.ConvertedType == nil) != (want == nil) || (want != nil && *el.ConvertedType != *want) {
+			t.Errorf("column %s: converted type %v, want %v", col.Name, el.ConvertedType, want)
+		}
+		if kind := field.Type().Kind(); int32(kind) != int32(col.Type) {
+			t.Errorf("column %s: parquet-go reads it as %v", col.Name, kind)
+		}
+	}
+	return f
+}
+
+// referenceRows reads every row of f with parquet-go, converted to the
+// values checkRows expects.
+func referenceRows(t *testing.T, f *parquetgo.File) [][]interface{} {
+	t.Helper()
+	var out [][]interface{}
+	for g, group := range f.RowGroups() {
+		rows := group.Rows()
+		buf := make([]parquetgo.Row, 16)
+		read := int64(0)
+		for {
+			n, err := rows.ReadRows(buf)
+			for _, row := range buf[:n] {
+				values := make([]interface{}, len(row))
+				for _, v := range row {
+					values[v.Column()] = referenceValue(v)
+				}
+				out = append(out, values)
+			}
+			read += int64(n)
+			if err != nil {
+				break
+			}
+		}
+		rows.Close()
+		if read != group.NumRows() {
+			t.Fatalf("row group %d: parquet-go read %d of %d rows", g, read, group.NumRows())
+		}
+	}
+	if int64(len(out)) != f.NumRows() {
+		t.Fatalf("parquet-go read %d rows, footer says %d", len(out), f.NumRows())
+	}
+	return out
+}
+
+func referenceValue(v parquetgo.Value) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+	switch v.Kind() {
+	case parquetgo.Int32:
+		return v.Int32()
+	case parquetgo.Int64:
+		return v.Int64()
+	case parquetgo.Double:
+		return v.Double()
+	case parquetgo.ByteArray:
+		return string(v.ByteArray())
+	}
+	return fmt.Sprintf("unexpected %v", v.Kind())
+}
+
+func TestReferenceReaderNullRunsAndRowGroups(t *testing.T) {
+	rows := readingRows(53)
+	data := writeParquet(t, readingColumns, 10, rows)
+	f := openReference(t, data, readingColumns)
+	if n := len(f.RowGroups()); n != 6 {
+		t.Errorf("parquet-go sees %d row groups, want 6", n)
+	}
+	checkRows(t, referenceRows(t, f), rows)
+}
+
+func TestReferenceReaderLongLists(t *testing.T) {
+	// More than 15 columns and row groups, so the footer's lists need the
+	// long form of the list header.
+	var columns []Column
+	for i := 0; i < 20; i++ {
+		columns = append(columns, Column{Name: fmt.Sprintf("c%02d", i), Type: Int64, Optional: i%2 == 1})
+	}
+	var rows [][]interface{}
+	for r := 0; r < 40; r++ {
+		row := make([]interface{}, len(columns))
+		for c := range row {
+			if c%2 == 0 || r%3 != 0 {
+				row[c] = int64(r*100 + c)
+			}
+		}
+		rows = append(rows, row)
+	}
+	data := writeParquet(t, columns, 2, rows)
+	f := openReference(t, data, columns)
+	if n := len(f.RowGroups()); n != 20 {
+		t.Errorf("parquet-go sees %d row groups, want 20", n)
+	}
+	checkRows(t, referenceRows(t, f), rows)
+}
+
+func TestReferenceReaderEmptyFile(t *testing.T) {
+	data := writeParquet(t, readingColumns, 0, nil)
+	f := openReference(t, data, readingColumns)
+	if f.NumRows() != 0 || len(f.RowGroups()) != 0 {
+		t.Errorf("parquet-go sees %d rows in %d row groups, want none", f.NumRows(), len(f.RowGroups()))
+	}
+}
+
+func TestReferenceReaderGoldenFile(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "readings.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := openReference(t, data, readingColumns)
+	checkRows(t, referenceRows(t, f), readingRows(9))
+
+	if got := f.Metadata().CreatedBy; got != "synthetic_sensors microservice-b" {
+		t.Errorf("created_by = %q", got)
+	}
+	if got := f.Metadata().Version; got != 1 {
+		t.Errorf("version = %d, want 1", got)
+	}
+	for _, group := range f.Metadata().RowGroups {
+		for _, chunk := range group.Columns {
+			if chunk.MetaData.Codec != format.Uncompressed {
+				t.Errorf("column %v uses codec %v", chunk.MetaData.PathInSchema, chunk.MetaData.Codec)
+			}
+		}
+	}
+}
+
+// The timestamp column holds microseconds since the epoch, which parquet-go
+// converts back to the original time.
+func TestReferenceReaderTimestamps(t *testing.T) {
+	at := time.Date(2024, 1, 15, 10, 30, 0, 123456000, time.UTC)
+	columns := []Column{{Name: "timestamp", Type: Int64, Annotation: TimestampMicros}}
+	data := writeParquet(t, columns, 0, [][]interface{}{{at}})
+
+	type row struct {
+		Timestamp time.Time `parquet:"timestamp,timestamp(microsecond)"`
+	}
+	got, err := parquetgo.Read[row](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !got[0].Timestamp.Equal(at) {
+		t.Errorf("parquet-go read %v, want %v", got, at)
+	}
+}
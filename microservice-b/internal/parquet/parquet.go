@@ -0,0 +1,347 @@
+// Package parquet writes flat Parquet files: uncompressed, PLAIN encoded,
+// with one data page per column and row group. It supports what exporting
+// sensor readings needs and nothing more. Rows are buffered one row group
+// at a time, so files of any size can be streamed.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is a Parquet physical type.
+type Type int32
+
+const (
+	Int32     Type = 1
+	Int64     Type = 2
+	Double    Type = 5
+	ByteArray Type = 6
+)
+
+// Annotation is the logical meaning of a column's physical type.
+type Annotation int
+
+const (
+	None            Annotation = iota
+	String                     // UTF-8 ByteArray
+	TimestampMicros            // Int64 microseconds since the Unix epoch, UTC
+)
+
+// Column describes one column of a flat schema.
+type Column struct {
+	Name       string
+	Type       Type
+	Annotation Annotation
+	Optional   bool
+}
+
+// DefaultRowGroupSize is the number of rows buffered before a row group is
+// written.
+const DefaultRowGroupSize = 65536
+
+const magic = "PAR1"
+
+// Parquet enum values used in headers and metadata.
+const (
+	pageTypeData      = 0
+	encodingPlain     = 0
+	encodingRLE       = 3
+	repetitionReq     = 0
+	repetitionOpt     = 1
+	convertedUTF8     = 0
+	convertedTSMicros = 10
+	codecUncompressed = 0
+)
+
+type Writer struct {
+	w            io.Writer
+	offset       int64
+	columns      []Column
+	buffers      []columnBuffer
+	rows         int // in the current row group
+	rowGroupSize int
+	groups       []rowGroup
+	err          error
+}
+
+type columnBuffer struct {
+	values bytes.Buffer
+	defs   []byte // definition levels of an optional column
+}
+
+type rowGroup struct {
+	rows   int64
+	chunks []chunk
+}
+
+type chunk struct {
+	offset int64
+	size   int64
+}
+
+// NewWriter starts a file with the given columns on w. Row groups hold
+// rowGroupSize rows, or DefaultRowGroupSize if it is not positive.
+func NewWriter(w io.Writer, columns []Column, rowGroupSize int) (*Writer, error) {
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	pw := &Writer{
+		w:            w,
+		columns:      columns,
+		buffers:      make([]columnBuffer, len(columns)),
+		rowGroupSize: rowGroupSize,
+	}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (w *Writer) write(b []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+	return err
+}
+
+// Write appends a row with one value per column: int32 or int for Int32,
+// int64, int or time.Time for Int64, float64 for Double and string or
+// []byte for ByteArray. Optional columns accept nil.
+func (w *Writer) Write(row ...interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, want %d", len(row), len(w.columns))
+	}
+	for i, v := range row {
+		if err := w.buffers[i].add(w.columns[i], v); err != nil {
+			return err
+		}
+	}
+	w.rows++
+	if w.rows == w.rowGroupSize {
+		return w.flushRowGroup()
+	}
+	return nil
+}
+
+func (b *columnBuffer) add(col Column, v interface{}) error {
+	if v == nil {
+		if !col.Optional {
+			return fmt.Errorf("parquet: column %s is required", col.Name)
+		}
+		b.defs = append(b.defs, 0)
+		return nil
+	}
+	if col.Optional {
+		b.defs = append(b.defs, 1)
+	}
+
+	var buf [8]byte
+	switch col.Type {
+	case Int32:
+		var n int32
+		switch v := v.(type) {
+		case int32:
+			n = v
+		case int:
+			n = int32(v)
+		default:
+			return fmt.Errorf("parquet: column %s: cannot write %T as INT32", col.Name, v)
+		}
+		binary.LittleEndian.PutUint32(buf[:4], uint32(n))
+		b.values.Write(buf[:4])
+	case Int64:
+		var n int64
+		switch v := v.(type) {
+		case int64:
+			n = v
+		case int:
+			n = int64(v)
+		case time.Time:
+			n = v.UnixMicro()
+		default:
+			return fmt.Errorf("parquet: column %s: cannot write %T as INT64", col.Name, v)
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(n))
+		b.values.Write(buf[:])
+	case Double:
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("parquet: column %s: cannot write %T as DOUBLE", col.Name, v)
+		}
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+		b.values.Write(buf[:])
+	case ByteArray:
+		var s []byte
+		switch v := v.(type) {
+		case string:
+			s = []byte(v)
+		case []byte:
+			s = v
+		default:
+			return fmt.Errorf("parquet: column %s: cannot write %T as BYTE_ARRAY", col.Name, v)
+		}
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(s)))
+		b.values.Write(buf[:4])
+		b.values.Write(s)
+	default:
+		return fmt.Errorf("parquet: column %s has unsupported type %d", col.Name, col.Type)
+	}
+	return nil
+}
+
+// flushRowGroup writes the buffered rows as a row group with one data page
+// per column.
+func (w *Writer) flushRowGroup() error {
+	group := rowGroup{rows: int64(w.rows)}
+	for i, col := range w.columns {
+		b := &w.buffers[i]
+
+		var data bytes.Buffer
+		if col.Optional {
+			levels := encodeLevels(b.defs)
+			var n [4]byte
+			binary.LittleEndian.PutUint32(n[:], uint32(len(levels)))
+			data.Write(n[:])
+			data.Write(levels)
+		}
+		data.Write(b.values.Bytes())
+
+		var h compactWriter
+		h.structBegin(0)
+		h.i32(1, pageTypeData)
+		h.i32(2, int32(data.Len()))
+		h.i32(3, int32(data.Len()))
+		h.structBegin(5)
+		h.i32(1, int32(w.rows))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.structEnd()
+		h.structEnd()
+
+		c := chunk{offset: w.offset, size: int64(h.buf.Len() + data.Len())}
+		if err := w.write(h.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := w.write(data.Bytes()); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, c)
+
+		b.values.Reset()
+		b.defs = b.defs[:0]
+	}
+	w.groups = append(w.groups, group)
+	w.rows = 0
+	return nil
+}
+
+// encodeLevels encodes definition levels of bit width 1 as RLE runs of the
+// RLE/bit-packing hybrid encoding.
+func encodeLevels(levels []byte) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		out = append(out, levels[i])
+		i = j
+	}
+	return out
+}
+
+// Close writes the buffered rows and the file footer. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.rows > 0 {
+		if err := w.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	var m compactWriter
+	var total int64
+	for _, g := range w.groups {
+		total += g.rows
+	}
+
+	m.structBegin(0)
+	m.i32(1, 1) // version
+	m.listBegin(2, ctStruct, len(w.columns)+1)
+	m.structBegin(0)
+	m.str(4, "schema")
+	m.i32(5, int32(len(w.columns)))
+	m.structEnd()
+	for _, col := range w.columns {
+		m.structBegin(0)
+		m.i32(1, int32(col.Type))
+		if col.Optional {
+			m.i32(3, repetitionOpt)
+		} else {
+			m.i32(3, repetitionReq)
+		}
+		m.str(4, col.Name)
+		switch col.Annotation {
+		case String:
+			m.i32(6, convertedUTF8)
+		case TimestampMicros:
+			m.i32(6, convertedTSMicros)
+		}
+		m.structEnd()
+	}
+	m.i64(3, total)
+	m.listBegin(4, ctStruct, len(w.groups))
+	for _, g := range w.groups {
+		var size int64
+		m.structBegin(0)
+		m.listBegin(1, ctStruct, len(g.chunks))
+		for i, c := range g.chunks {
+			col := w.columns[i]
+			size += c.size
+			m.structBegin(0)
+			m.i64(2, c.offset)
+			m.structBegin(3)
+			m.i32(1, int32(col.Type))
+			m.i32List(2, []int32{encodingPlain, encodingRLE})
+			m.strList(3, []string{col.Name})
+			m.i32(4, codecUncompressed)
+			m.i64(5, g.rows)
+			m.i64(6, c.size)
+			m.i64(7, c.size)
+			m.i64(9, c.offset)
+			m.structEnd()
+			m.structEnd()
+		}
+		m.i64(2, size)
+		m.i64(3, g.rows)
+		m.structEnd()
+	}
+	m.str(6, "synthetic_sensors microservice-b")
+	m.structEnd()
+
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(m.buf.Len()))
+	if err := w.write(m.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := w.write(n[:]); err != nil {
+		return err
+	}
+	return w.write([]byte(magic))
+}
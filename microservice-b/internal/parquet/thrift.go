@@ -0,0 +1,97 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type IDs.
+const (
+	ctI32    = 5
+	ctI64    = 6
+	ctBinary = 8
+	ctList   = 9
+	ctStruct = 12
+)
+
+// compactWriter encodes the Thrift compact protocol, which Parquet uses for
+// page headers and the file footer. Only what those structures need is
+// supported.
+type compactWriter struct {
+	buf  bytes.Buffer
+	last []int16 // last field ID of each open struct
+}
+
+func (w *compactWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func zigzag(v int64) uint64 { return uint64((v << 1) ^ (v >> 63)) }
+
+func (w *compactWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.field(id, ctI32)
+	w.varint(zigzag(int64(v)))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.field(id, ctI64)
+	w.varint(zigzag(v))
+}
+
+func (w *compactWriter) str(id int16, s string) {
+	w.field(id, ctBinary)
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// structBegin starts a struct, as field id of the enclosing struct or, with
+// id 0, as a list element or the top-level struct.
+func (w *compactWriter) structBegin(id int16) {
+	if id != 0 {
+		w.field(id, ctStruct)
+	}
+	w.last = append(w.last, 0)
+}
+
+func (w *compactWriter) structEnd() {
+	w.buf.WriteByte(0) // stop
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *compactWriter) listBegin(id int16, elemType byte, n int) {
+	w.field(id, ctList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elemType)
+	w.varint(uint64(n))
+}
+
+func (w *compactWriter) i32List(id int16, vs []int32) {
+	w.listBegin(id, ctI32, len(vs))
+	for _, v := range vs {
+		w.varint(zigzag(int64(v)))
+	}
+}
+
+func (w *compactWriter) strList(id int16, vs []string) {
+	w.listBegin(id, ctBinary, len(vs))
+	for _, v := range vs {
+		w.varint(uint64(len(v)))
+		w.buf.WriteString(v)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"strings"
 	
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/domain"
 	sharedDomain "github.com/glitchdawg/synthetic_sensors/shared/domain"
 )
@@ -20,16 +20,78 @@ func NewPostgresRepository(db *sql.DB) domain.SensorReadingRepository {
 }
 
 func (r *postgresRepository) Create(ctx context.Context, reading *sharedDomain.SensorReading) error {
-	query := `INSERT INTO sensor_readings (id1, id2, sensor_type, value, ts) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.ExecContext(ctx, query, reading.ID1, reading.ID2, reading.SensorType, reading.Value, reading.Timestamp)
+	// A reading whose reading_id is already stored is a re-send and is
+	// skipped; readings without one are always inserted.
+	query := `INSERT INTO sensor_readings (id1, id2, sensor_type, value, unit, ts, reading_id) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (reading_id) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, reading.ID1, reading.ID2, reading.SensorType, reading.Value, reading.Unit, reading.Timestamp, reading.ReadingID)
+	return classify(err)
+}
+
+// classify marks data exceptions (class 22) and integrity constraint
+// violations (class 23) as domain.ErrInvalidReading.
+func classify(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Class() {
+		case "22", "23":
+			return fmt.Errorf("%w: %v", domain.ErrInvalidReading, err)
+		}
+	}
 	return err
 }
 
+// CreateBatch loads readings with the COPY protocol in a single
+// transaction. COPY cannot skip conflicting rows, so the readings are
+// copied into a temporary table and moved over with an INSERT that, like
+// Create, skips readings whose reading_id is already stored.
+func (r *postgresRepository) CreateBatch(ctx context.Context, readings []sharedDomain.SensorReading) error {
+	if len(readings) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE sensor_readings_copy ON COMMIT DROP AS
+		SELECT id1, id2, sensor_type, value, unit, ts, reading_id FROM sensor_readings WITH NO DATA`); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("sensor_readings_copy", "id1", "id2", "sensor_type", "value", "unit", "ts", "reading_id"))
+	if err != nil {
+		return err
+	}
+	for _, reading := range readings {
+		if _, err := stmt.ExecContext(ctx, reading.ID1, reading.ID2, reading.SensorType, reading.Value, reading.Unit, reading.Timestamp, reading.ReadingID); err != nil {
+			stmt.Close()
+			return classify(err)
+		}
+	}
+	// The final Exec without arguments flushes the buffered rows.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return classify(err)
+	}
+	if err := stmt.Close(); err != nil {
+		return classify(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO sensor_readings (id1, id2, sensor_type, value, unit, ts, reading_id)
+		SELECT id1, id2, sensor_type, value, unit, ts, reading_id FROM sensor_readings_copy
+		ON CONFLICT (reading_id) DO NOTHING`); err != nil {
+		return classify(err)
+	}
+	return tx.Commit()
+}
+
 func (r *postgresRepository) GetByID(ctx context.Context, id int) (*sharedDomain.SensorReading, error) {
 	reading := &sharedDomain.SensorReading{}
-	query := `SELECT id, id1, id2, sensor_type, value, ts FROM sensor_readings WHERE id = $1`
+	query := `SELECT id, id1, id2, sensor_type, value, unit, ts, reading_id FROM sensor_readings WHERE id = $1`
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&reading.ID, &reading.ID1, &reading.ID2, &reading.SensorType, &reading.Value, &reading.Timestamp,
+		&reading.ID, &reading.ID1, &reading.ID2, &reading.SensorType, &reading.Value, &reading.Unit, &reading.Timestamp, &reading.ReadingID,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -38,30 +100,8 @@ func (r *postgresRepository) GetByID(ctx context.Context, id int) (*sharedDomain
 }
 
 func (r *postgresRepository) GetByFilter(ctx context.Context, filter *sharedDomain.SensorReadingFilter) (*sharedDomain.PaginatedResponse, error) {
-	var conditions []string
-	var args []interface{}
-	argCount := 1
-
-	if filter.ID1 != nil {
-		conditions = append(conditions, fmt.Sprintf("id1 = $%d", argCount))
-		args = append(args, *filter.ID1)
-		argCount++
-	}
-	if filter.ID2 != nil {
-		conditions = append(conditions, fmt.Sprintf("id2 = $%d", argCount))
-		args = append(args, *filter.ID2)
-		argCount++
-	}
-	if filter.From != nil {
-		conditions = append(conditions, fmt.Sprintf("ts >= $%d", argCount))
-		args = append(args, *filter.From)
-		argCount++
-	}
-	if filter.To != nil {
-		conditions = append(conditions, fmt.Sprintf("ts <= $%d", argCount))
-		args = append(args, *filter.To)
-		argCount++
-	}
+	conditions, args := filterConditions(filter)
+	argCount := len(args) + 1
 
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -90,7 +130,7 @@ func (r *postgresRepository) GetByFilter(ctx context.Context, filter *sharedDoma
 	}
 
 	// Fetch paginated data
-	query := fmt.Sprintf("SELECT id, id1, id2, sensor_type, value, ts FROM sensor_readings %s ORDER BY ts DESC LIMIT $%d OFFSET $%d", whereClause, argCount, argCount+1)
+	query := fmt.Sprintf("SELECT id, id1, id2, sensor_type, value, unit, ts, reading_id FROM sensor_readings %s ORDER BY ts DESC LIMIT $%d OFFSET $%d", whereClause, argCount, argCount+1)
 	args = append(args, filter.PageSize, offset)
 	
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -102,7 +142,7 @@ func (r *postgresRepository) GetByFilter(ctx context.Context, filter *sharedDoma
 	var readings []sharedDomain.SensorReading
 	for rows.Next() {
 		var reading sharedDomain.SensorReading
-		err := rows.Scan(&reading.ID, &reading.ID1, &reading.ID2, &reading.SensorType, &reading.Value, &reading.Timestamp)
+		err := rows.Scan(&reading.ID, &reading.ID1, &reading.ID2, &reading.SensorType, &reading.Value, &reading.Unit, &reading.Timestamp, &reading.ReadingID)
 		if err != nil {
 			return nil, err
 		}
@@ -119,8 +159,8 @@ func (r *postgresRepository) GetByFilter(ctx context.Context, filter *sharedDoma
 }
 
 func (r *postgresRepository) Update(ctx context.Context, id int, reading *sharedDomain.SensorReading) error {
-	query := `UPDATE sensor_readings SET id1 = $1, id2 = $2, sensor_type = $3, value = $4, ts = $5 WHERE id = $6`
-	result, err := r.db.ExecContext(ctx, query, reading.ID1, reading.ID2, reading.SensorType, reading.Value, reading.Timestamp, id)
+	query := `UPDATE sensor_readings SET id1 = $1, id2 = $2, sensor_type = $3, value = $4, unit = $5, ts = $6 WHERE id = $7`
+	result, err := r.db.ExecContext(ctx, query, reading.ID1, reading.ID2, reading.SensorType, reading.Value, reading.Unit, reading.Timestamp, id)
 	if err != nil {
 		return err
 	}
@@ -135,6 +175,22 @@ func (r *postgresRepository) Update(ctx context.Context, id int, reading *shared
 }
 
 func (r *postgresRepository) Delete(ctx context.Context, filter *sharedDomain.SensorReadingFilter) (int64, error) {
+	conditions, args := filterConditions(filter)
+	if len(conditions) == 0 {
+		return 0, fmt.Errorf("at least one filter condition is required for deletion")
+	}
+
+	query := fmt.Sprintf("DELETE FROM sensor_readings WHERE %s", strings.Join(conditions, " AND "))
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// filterConditions returns the WHERE conditions for filter's ID and time
+// range fields, with their arguments numbered from $1.
+func filterConditions(filter *sharedDomain.SensorReadingFilter) ([]string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 	argCount := 1
@@ -159,15 +215,183 @@ func (r *postgresRepository) Delete(ctx context.Context, filter *sharedDomain.Se
 		args = append(args, *filter.To)
 		argCount++
 	}
+	return conditions, args
+}
 
-	if len(conditions) == 0 {
-		return 0, fmt.Errorf("at least one filter condition is required for deletion")
+// exportFetchSize is how many rows Export fetches from its cursor at a time.
+const exportFetchSize = 1000
+
+// Export calls fn for every reading matching filter's ID and time range
+// fields, oldest first. Rows are read through a server-side cursor, so
+// neither side holds the whole result in memory. Pagination fields are
+// ignored.
+func (r *postgresRepository) Export(ctx context.Context, filter *sharedDomain.SensorReadingFilter, fn func(*sharedDomain.SensorReading) error) error {
+	conditions, args := filterConditions(filter)
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query := fmt.Sprintf("DELETE FROM sensor_readings WHERE %s", strings.Join(conditions, " AND "))
-	result, err := r.db.ExecContext(ctx, query, args...)
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("DECLARE export_cursor NO SCROLL CURSOR FOR SELECT id, id1, id2, sensor_type, value, unit, ts, reading_id FROM sensor_readings %s ORDER BY ts, id", whereClause)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM export_cursor", exportFetchSize)
+	for {
+		n, err := exportRows(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+// exportRows runs one FETCH of Export and passes its rows to fn. It returns
+// how many rows there were.
+func exportRows(ctx context.Context, tx *sql.Tx, fetch string, fn func(*sharedDomain.SensorReading) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
 	if err != nil {
 		return 0, err
 	}
-	return result.RowsAffected()
-}
\ No newline at end of file
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var reading sharedDomain.SensorReading
+		if err := rows.Scan(&reading.ID, &reading.ID1, &reading.ID2, &reading.SensorType, &reading.Value, &reading.Unit, &reading.Timestamp, &reading.ReadingID); err != nil {
+			return n, err
+		}
+		if err := fn(&reading); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// maxAggregateRows bounds the buckets Aggregate returns.
+const maxAggregateRows = 10000
+
+// aggregateExpr returns the SQL for an aggregate function. Percentiles take
+// their fraction as argument $arg.
+func aggregateExpr(fn string, arg int) (string, bool) {
+	switch fn {
+	case "avg":
+		return "AVG(value)", true
+	case "min":
+		return "MIN(value)", true
+	case "max":
+		return "MAX(value)", true
+	case "sum":
+		return "SUM(value)", true
+	case "count":
+		return "COUNT(*)::double precision", true
+	case "stddev":
+		return "STDDEV_SAMP(value)", true
+	case "first":
+		return "(ARRAY_AGG(value ORDER BY ts, id))[1]", true
+	case "last":
+		return "(ARRAY_AGG(value ORDER BY ts DESC, id DESC))[1]", true
+	}
+	if _, ok := sharedDomain.ParsePercentile(fn); ok {
+		return fmt.Sprintf("PERCENTILE_CONT($%d::double precision) WITHIN GROUP (ORDER BY value)", arg), true
+	}
+	return "", false
+}
+
+// Aggregate buckets the readings matching the query's filter by time, and
+// by its group-by columns, and computes its functions over each bucket in
+// SQL. Buckets are aligned to midnight UTC and returned oldest first.
+func (r *postgresRepository) Aggregate(ctx context.Context, q *sharedDomain.AggregateQuery) ([]sharedDomain.AggregateBucket, error) {
+	size, ok := sharedDomain.AggregateBuckets[q.Bucket]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown bucket %q", domain.ErrInvalidAggregate, q.Bucket)
+	}
+
+	conditions, args := filterConditions(&q.Filter)
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, size.Seconds())
+	columns := []string{fmt.Sprintf("date_bin($%d::double precision * INTERVAL '1 second', ts, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket", len(args))}
+	groups := []string{"bucket"}
+	for _, col := range q.GroupBy {
+		switch col {
+		case "id1", "id2", "sensor_type":
+		default:
+			return nil, fmt.Errorf("%w: cannot group by %q", domain.ErrInvalidAggregate, col)
+		}
+		columns = append(columns, col)
+		groups = append(groups, col)
+	}
+	for _, fn := range q.Functions {
+		expr, ok := aggregateExpr(fn, len(args)+1)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown function %q", domain.ErrInvalidAggregate, fn)
+		}
+		if p, ok := sharedDomain.ParsePercentile(fn); ok {
+			args = append(args, p)
+		}
+		columns = append(columns, expr)
+	}
+
+	args = append(args, maxAggregateRows+1)
+	query := fmt.Sprintf("SELECT %s FROM sensor_readings %s GROUP BY %s ORDER BY %s LIMIT $%d",
+		strings.Join(columns, ", "), whereClause, strings.Join(groups, ", "), strings.Join(groups, ", "), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []sharedDomain.AggregateBucket
+	for rows.Next() {
+		if len(buckets) == maxAggregateRows {
+			return nil, fmt.Errorf("%w: more than %d buckets; narrow the time range, group by fewer columns or use a larger bucket", domain.ErrInvalidAggregate, maxAggregateRows)
+		}
+
+		var b sharedDomain.AggregateBucket
+		dest := []interface{}{&b.Bucket}
+		for _, col := range q.GroupBy {
+			switch col {
+			case "id1":
+				dest = append(dest, &b.ID1)
+			case "id2":
+				dest = append(dest, &b.ID2)
+			case "sensor_type":
+				dest = append(dest, &b.SensorType)
+			}
+		}
+		values := make([]sql.NullFloat64, len(q.Functions))
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		b.Values = make(map[string]*float64, len(values))
+		for i, v := range values {
+			if v.Valid {
+				f := v.Float64
+				b.Values[q.Functions[i]] = &f
+			} else {
+				b.Values[q.Functions[i]] = nil
+			}
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/domain"
+	sharedDomain "github.com/glitchdawg/synthetic_sensors/shared/domain"
+	"github.com/lib/pq"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct {
+		err     error
+		invalid bool
+	}{
+		{&pq.Error{Code: "22001"}, true},  // string_data_right_truncation
+		{&pq.Error{Code: "22P02"}, true},  // invalid_text_representation
+		{&pq.Error{Code: "23502"}, true},  // not_null_violation
+		{&pq.Error{Code: "23505"}, true},  // unique_violation
+		{&pq.Error{Code: "40001"}, false}, // serialization_failure
+		{&pq.Error{Code: "53300"}, false}, // too_many_connections
+		{&pq.Error{Code: "57014"}, false}, // query_canceled
+		{sql.ErrConnDone, false},
+		{context.Canceled, false},
+	} {
+		got := classify(tt.err)
+		if errors.Is(got, domain.ErrInvalidReading) != tt.invalid {
+			t.Errorf("classify(%v) = %v, want invalid = %v", tt.err, got, tt.invalid)
+		}
+		if !tt.invalid && got != tt.err {
+			t.Errorf("classify(%v) = %v, want the error unchanged", tt.err, got)
+		}
+	}
+	if err := classify(nil); err != nil {
+		t.Errorf("classify(nil) = %v", err)
+	}
+}
+
+// testRepository connects to TEST_DATABASE_URL and creates the schema in a
+// schema of its own, dropped when the test ends. Tests using it are
+// skipped when the variable is unset.
+func testRepository(t *testing.T) domain.SensorReadingRepository {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// One connection, so the search_path below applies to every query.
+	db.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	seed, err := os.ReadFile("../db/seed.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{"CREATE SCHEMA " + schema, "SET search_path TO " + schema, string(seed)} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { db.Exec("DROP SCHEMA " + schema + " CASCADE") })
+	return NewPostgresRepository(db)
+}
+
+func testReading(id1 string, id2 int, readingID string) sharedDomain.SensorReading {
+	r := sharedDomain.SensorReading{
+		ID1: id1, ID2: id2, SensorType: "temperature", Value: 21.5, Unit: "°C",
+		Timestamp: time.Date(2024, 1, 15, 10, 30, id2, 0, time.UTC),
+	}
+	if readingID != "" {
+		r.ReadingID = &readingID
+	}
+	return r
+}
+
+func exportAll(t *testing.T, repo domain.SensorReadingRepository) []sharedDomain.SensorReading {
+	t.Helper()
+	var out []sharedDomain.SensorReading
+	err := repo.Export(context.Background(), &sharedDomain.SensorReadingFilter{}, func(r *sharedDomain.SensorReading) error {
+		out = append(out, *r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestCreateBatchSkipsStoredReadingIDs(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+
+	first := []sharedDomain.SensorReading{
+		testReading("A", 1, "k-1"),
+		testReading("A", 2, "k-2"),
+		testReading("A", 3, ""),
+	}
+	if err := repo.CreateBatch(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	// k-2 is a re-send; the reading without an ID and k-3, a copy of
+	// k-1's data under a key of its own, are new.
+	again := []sharedDomain.SensorReading{
+		testReading("A", 2, "k-2"),
+		testReading("A", 3, ""),
+		testReading("A", 1, "k-3"),
+	}
+	if err := repo.CreateBatch(ctx, again); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := exportAll(t, repo)
+	if len(stored) != 5 {
+		t.Fatalf("stored %d readings, want 5: %+v", len(stored), stored)
+	}
+	for i := 1; i < len(stored); i++ {
+		if stored[i].Timestamp.Before(stored[i-1].Timestamp) {
+			t.Errorf("export is not oldest first: %v before %v", stored[i-1].Timestamp, stored[i].Timestamp)
+		}
+	}
+}
+
+func TestCreateBatchRejectsInvalidDataAtomically(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+
+	readings := []sharedDomain.SensorReading{testReading("A", 1, "k-1"), testReading("A", 2, "k-2")}
+	readings[1].Unit = strings.Repeat("u", 21) // longer than VARCHAR(20)
+	err := repo.CreateBatch(ctx, readings)
+	if !errors.Is(err, domain.ErrInvalidReading) {
+		t.Fatalf("CreateBatch = %v, want ErrInvalidReading", err)
+	}
+	if stored := exportAll(t, repo); len(stored) != 0 {
+		t.Errorf("a failed batch stored %d readings", len(stored))
+	}
+}
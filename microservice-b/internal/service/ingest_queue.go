@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	sharedDomain "github.com/glitchdawg/synthetic_sensors/shared/domain"
+)
+
+// IngestQueueConfig sizes the ingest queue.
+type IngestQueueConfig struct {
+	Capacity      int           // queued requests before Enqueue blocks
+	Workers       int           // goroutines writing to the database
+	FlushSize     int           // readings that trigger a flush
+	FlushInterval time.Duration // how long a partial flush may wait
+}
+
+// DefaultIngestQueueConfig is used for settings that are not configured.
+var DefaultIngestQueueConfig = IngestQueueConfig{
+	Capacity:      1024,
+	Workers:       4,
+	FlushSize:     1000,
+	FlushInterval: 100 * time.Millisecond,
+}
+
+// IngestQueue decouples the gRPC streams from the database. Each worker
+// collects queued requests until it holds FlushSize readings or the oldest
+// has waited FlushInterval, then stores them with one CreateReadings call.
+// When the queue is full, Enqueue blocks, so streams stop reading and gRPC
+// flow control pushes back on the generators.
+type IngestQueue struct {
+	sensors *SensorService
+	cfg     IngestQueueConfig
+	jobs    chan *ingestJob
+
+	flushes atomic.Uint64
+	stored  atomic.Uint64
+	failed  atomic.Uint64
+}
+
+type ingestJob struct {
+	readings []sharedDomain.SensorReading
+	done     func(error)
+}
+
+// IngestQueueStats describes the queue for /health.
+type IngestQueueStats struct {
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+	Workers  int    `json:"workers"`
+	Flushes  uint64 `json:"flushes"`
+	Stored   uint64 `json:"stored"`
+	Failed   uint64 `json:"failed"`
+}
+
+// NewIngestQueue starts cfg.Workers workers; zero settings take their
+// default.
+func NewIngestQueue(sensors *SensorService, cfg IngestQueueConfig) *IngestQueue {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = DefaultIngestQueueConfig.Capacity
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultIngestQueueConfig.Workers
+	}
+	if cfg.FlushSize <= 0 {
+		cfg.FlushSize = DefaultIngestQueueConfig.FlushSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultIngestQueueConfig.FlushInterval
+	}
+
+	q := &IngestQueue{
+		sensors: sensors,
+		cfg:     cfg,
+		jobs:    make(chan *ingestJob, cfg.Capacity),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue queues readings to be stored and calls done with the result once
+// they have been written. It blocks while the queue is full and returns
+// ctx's error if ctx is done first, in which case done is never called.
+func (q *IngestQueue) Enqueue(ctx context.Context, readings []sharedDomain.SensorReading, done func(error)) error {
+	select {
+	case q.jobs <- &ingestJob{readings: readings, done: done}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *IngestQueue) Stats() IngestQueueStats {
+	return IngestQueueStats{
+		Depth:    len(q.jobs),
+		Capacity: q.cfg.Capacity,
+		Workers:  q.cfg.Workers,
+		Flushes:  q.flushes.Load(),
+		Stored:   q.stored.Load(),
+		Failed:   q.failed.Load(),
+	}
+}
+
+func (q *IngestQueue) work() {
+	timer := time.NewTimer(q.cfg.FlushInterval)
+	timer.Stop()
+
+	var pending []*ingestJob
+	readings := 0
+	for {
+		select {
+		case job := <-q.jobs:
+			if len(pending) == 0 {
+				timer.Reset(q.cfg.FlushInterval)
+			}
+			pending = append(pending, job)
+			readings += len(job.readings)
+			if readings < q.cfg.FlushSize {
+				continue
+			}
+			timer.Stop()
+		case <-timer.C:
+		}
+		q.flush(pending, readings)
+		pending, readings = nil, 0
+	}
+}
+
+// flush stores the readings of jobs together. If the database rejects the
+// data, the jobs are stored one by one so that only the offending ones fail.
+func (q *IngestQueue) flush(jobs []*ingestJob, n int) {
+	readings := make([]sharedDomain.SensorReading, 0, n)
+	for _, job := range jobs {
+		readings = append(readings, job.readings...)
+	}
+
+	ctx := context.Background()
+	err := q.sensors.CreateReadings(ctx, readings)
+	q.flushes.Add(1)
+	if errors.Is(err, ErrInvalidReading) && len(jobs) > 1 {
+		for _, job := range jobs {
+			q.finish(job, q.sensors.CreateReadings(ctx, job.readings))
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("failed to flush %d readings: %v", n, err)
+	}
+	for _, job := range jobs {
+		q.finish(job, err)
+	}
+}
+
+func (q *IngestQueue) finish(job *ingestJob, err error) {
+	if err != nil {
+		q.failed.Add(uint64(len(job.readings)))
+	} else {
+		q.stored.Add(uint64(len(job.readings)))
+	}
+	job.done(err)
+}
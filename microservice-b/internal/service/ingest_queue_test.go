@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/domain"
+	sharedDomain "github.com/glitchdawg/synthetic_sensors/shared/domain"
+)
+
+// batchRepository records CreateBatch calls. Batches containing a reading
+// with ID1 "BAD" fail with ErrInvalidReading. While gate is set, CreateBatch
+// signals entered and waits for gate to be closed.
+type batchRepository struct {
+	domain.SensorReadingRepository
+
+	gate    chan struct{}
+	entered chan struct{}
+
+	mu      sync.Mutex
+	batches [][]sharedDomain.SensorReading
+}
+
+func (r *batchRepository) CreateBatch(ctx context.Context, readings []sharedDomain.SensorReading) error {
+	if r.gate != nil {
+		r.entered <- struct{}{}
+		<-r.gate
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, readings)
+	for _, reading := range readings {
+		if reading.ID1 == "BAD" {
+			return fmt.Errorf("%w: value too long", ErrInvalidReading)
+		}
+	}
+	return nil
+}
+
+func (r *batchRepository) batchSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sizes := make([]int, len(r.batches))
+	for i, b := range r.batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
+
+func queueReadings(id1 string, n int) []sharedDomain.SensorReading {
+	readings := make([]sharedDomain.SensorReading, n)
+	for i := range readings {
+		readings[i] = sharedDomain.SensorReading{ID1: id1, ID2: i, SensorType: "temperature", Timestamp: time.Now()}
+	}
+	return readings
+}
+
+// enqueue queues readings and returns a channel that receives their result.
+func enqueue(t *testing.T, q *IngestQueue, readings []sharedDomain.SensorReading) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	if err := q.Enqueue(context.Background(), readings, func(err error) { result <- err }); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func wait(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("readings were not stored")
+		return nil
+	}
+}
+
+func TestIngestQueueFlushesOnSize(t *testing.T) {
+	repo := &batchRepository{}
+	q := NewIngestQueue(NewSensorService(repo), IngestQueueConfig{Workers: 1, FlushSize: 5, FlushInterval: time.Hour})
+
+	first := enqueue(t, q, queueReadings("A", 2))
+	second := enqueue(t, q, queueReadings("B", 3))
+	for _, result := range []<-chan error{first, second} {
+		if err := wait(t, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sizes := repo.batchSizes(); len(sizes) != 1 || sizes[0] != 5 {
+		t.Errorf("batches of %v readings, want one of 5", sizes)
+	}
+	if stats := q.Stats(); stats.Flushes != 1 || stats.Stored != 5 || stats.Failed != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestIngestQueueFlushesAfterInterval(t *testing.T) {
+	repo := &batchRepository{}
+	const interval = 50 * time.Millisecond
+	q := NewIngestQueue(NewSensorService(repo), IngestQueueConfig{Workers: 1, FlushSize: 1000, FlushInterval: interval})
+
+	start := time.Now()
+	first := enqueue(t, q, queueReadings("A", 2))
+	second := enqueue(t, q, queueReadings("B", 1))
+	for _, result := range []<-chan error{first, second} {
+		if err := wait(t, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < interval {
+		t.Errorf("partial flush after %v, want it to wait %v", elapsed, interval)
+	}
+	if sizes := repo.batchSizes(); len(sizes) != 1 || sizes[0] != 3 {
+		t.Errorf("batches of %v readings, want one of 3", sizes)
+	}
+}
+
+func TestIngestQueueRetriesJobsOnInvalidReading(t *testing.T) {
+	repo := &batchRepository{}
+	q := NewIngestQueue(NewSensorService(repo), IngestQueueConfig{Workers: 1, FlushSize: 6, FlushInterval: time.Hour})
+
+	good := enqueue(t, q, queueReadings("A", 2))
+	bad := enqueue(t, q, queueReadings("BAD", 2))
+	alsoGood := enqueue(t, q, queueReadings("B", 2))
+	if err := wait(t, good); err != nil {
+		t.Errorf("valid job failed: %v", err)
+	}
+	if err := wait(t, bad); !errors.Is(err, ErrInvalidReading) {
+		t.Errorf("invalid job: %v, want ErrInvalidReading", err)
+	}
+	if err := wait(t, alsoGood); err != nil {
+		t.Errorf("valid job failed: %v", err)
+	}
+	// One combined attempt, then one per job.
+	if sizes := repo.batchSizes(); fmt.Sprint(sizes) != "[6 2 2 2]" {
+		t.Errorf("batches of %v readings, want [6 2 2 2]", sizes)
+	}
+	if stats := q.Stats(); stats.Flushes != 1 || stats.Stored != 4 || stats.Failed != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestIngestQueueDoesNotRetrySingleJob(t *testing.T) {
+	repo := &batchRepository{}
+	q := NewIngestQueue(NewSensorService(repo), IngestQueueConfig{Workers: 1, FlushSize: 2, FlushInterval: time.Hour})
+
+	if err := wait(t, enqueue(t, q, queueReadings("BAD", 2))); !errors.Is(err, ErrInvalidReading) {
+		t.Errorf("invalid job: %v, want ErrInvalidReading", err)
+	}
+	if sizes := repo.batchSizes(); len(sizes) != 1 {
+		t.Errorf("batches of %v readings, want a single attempt", sizes)
+	}
+}
+
+func TestIngestQueueBlocksWhenFull(t *testing.T) {
+	repo := &batchRepository{gate: make(chan struct{}), entered: make(chan struct{}, 1)}
+	q := NewIngestQueue(NewSensorService(repo), IngestQueueConfig{Capacity: 1, Workers: 1, FlushSize: 1, FlushInterval: time.Hour})
+
+	// The worker takes the first job and blocks writing it; the second
+	// fills the queue.
+	first := enqueue(t, q, queueReadings("A", 1))
+	<-repo.entered
+	second := enqueue(t, q, queueReadings("B", 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	called := false
+	err := q.Enqueue(ctx, queueReadings("C", 1), func(error) { called = true })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Enqueue on a full queue returned %v, want it to block until the deadline", err)
+	}
+	if depth := q.Stats().Depth; depth != 1 {
+		t.Errorf("queue depth %d, want 1", depth)
+	}
+
+	close(repo.gate)
+	<-repo.entered // the second job, once the first is written
+	for _, result := range []<-chan error{first, second} {
+		if err := wait(t, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if called {
+		t.Error("done was called for a reading that was never queued")
+	}
+	if sizes := repo.batchSizes(); len(sizes) != 2 {
+		t.Errorf("batches of %v readings, want the two queued jobs", sizes)
+	}
+}
@@ -3,12 +3,22 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/glitchdawg/synthetic_sensors/microservice-b/internal/domain"
 	sharedDomain "github.com/glitchdawg/synthetic_sensors/shared/domain"
 )
 
+// ErrInvalidReading is returned when readings are rejected by the database
+// because of their content; see domain.ErrInvalidReading.
+var ErrInvalidReading = domain.ErrInvalidReading
+
+// ErrInvalidAggregate is returned for aggregate queries with an unknown
+// bucket size, function or group-by column, or too many buckets; see
+// domain.ErrInvalidAggregate.
+var ErrInvalidAggregate = domain.ErrInvalidAggregate
+
 type SensorService struct {
 	repo domain.SensorReadingRepository
 }
@@ -24,10 +34,72 @@ func (s *SensorService) CreateReading(ctx context.Context, reading *sharedDomain
 	return s.repo.Create(ctx, reading)
 }
 
+// CreateReadings stores readings in a single round trip where possible.
+func (s *SensorService) CreateReadings(ctx context.Context, readings []sharedDomain.SensorReading) error {
+	for i := range readings {
+		if readings[i].Timestamp.IsZero() {
+			readings[i].Timestamp = time.Now().UTC()
+		}
+	}
+	return s.repo.CreateBatch(ctx, readings)
+}
+
 func (s *SensorService) GetReadings(ctx context.Context, filter *sharedDomain.SensorReadingFilter) (*sharedDomain.PaginatedResponse, error) {
 	return s.repo.GetByFilter(ctx, filter)
 }
 
+// ExportReadings streams the readings matching filter to fn, oldest first.
+func (s *SensorService) ExportReadings(ctx context.Context, filter *sharedDomain.SensorReadingFilter, fn func(*sharedDomain.SensorReading) error) error {
+	return s.repo.Export(ctx, filter, fn)
+}
+
+// AggregateReadings validates query and computes it. Repeated functions and
+// group-by columns are dropped; without functions, avg is computed.
+func (s *SensorService) AggregateReadings(ctx context.Context, query *sharedDomain.AggregateQuery) (*sharedDomain.AggregateResult, error) {
+	if _, ok := sharedDomain.AggregateBuckets[query.Bucket]; !ok {
+		return nil, fmt.Errorf("%w: unknown bucket %q, use 1m, 5m, 1h or 1d", ErrInvalidAggregate, query.Bucket)
+	}
+	query.Functions = dedupe(query.Functions)
+	if len(query.Functions) == 0 {
+		query.Functions = []string{"avg"}
+	}
+	for _, fn := range query.Functions {
+		if _, ok := sharedDomain.ParsePercentile(fn); !ok && !slices.Contains(sharedDomain.AggregateFunctions, fn) {
+			return nil, fmt.Errorf("%w: unknown function %q", ErrInvalidAggregate, fn)
+		}
+	}
+	query.GroupBy = dedupe(query.GroupBy)
+	for _, col := range query.GroupBy {
+		if !slices.Contains(sharedDomain.AggregateGroupBy, col) {
+			return nil, fmt.Errorf("%w: cannot group by %q", ErrInvalidAggregate, col)
+		}
+	}
+
+	buckets, err := s.repo.Aggregate(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if buckets == nil {
+		buckets = []sharedDomain.AggregateBucket{}
+	}
+	return &sharedDomain.AggregateResult{
+		Bucket:    query.Bucket,
+		Functions: query.Functions,
+		GroupBy:   query.GroupBy,
+		Data:      buckets,
+	}, nil
+}
+
+func dedupe(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func (s *SensorService) UpdateReading(ctx context.Context, id int, reading *sharedDomain.SensorReading) error {
 	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
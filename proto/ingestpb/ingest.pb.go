@@ -21,13 +21,66 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type IngestAck_Status int32
+
+const (
+	IngestAck_STORED   IngestAck_Status = 0 // stored; count readings were written
+	IngestAck_REJECTED IngestAck_Status = 1 // invalid data, retrying will not help
+	IngestAck_FAILED   IngestAck_Status = 2 // not stored, retry later
+)
+
+// Enum value maps for IngestAck_Status.
+var (
+	IngestAck_Status_name = map[int32]string{
+		0: "STORED",
+		1: "REJECTED",
+		2: "FAILED",
+	}
+	IngestAck_Status_value = map[string]int32{
+		"STORED":   0,
+		"REJECTED": 1,
+		"FAILED":   2,
+	}
+)
+
+func (x IngestAck_Status) Enum() *IngestAck_Status {
+	p := new(IngestAck_Status)
+	*p = x
+	return p
+}
+
+func (x IngestAck_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IngestAck_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_ingest_proto_enumTypes[0].Descriptor()
+}
+
+func (IngestAck_Status) Type() protoreflect.EnumType {
+	return &file_proto_ingest_proto_enumTypes[0]
+}
+
+func (x IngestAck_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IngestAck_Status.Descriptor instead.
+func (IngestAck_Status) EnumDescriptor() ([]byte, []int) {
+	return file_proto_ingest_proto_rawDescGZIP(), []int{5, 0}
+}
+
 type Reading struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         float64                `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
-	SensorType    string                 `protobuf:"bytes,2,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
-	Id1           string                 `protobuf:"bytes,3,opt,name=id1,proto3" json:"id1,omitempty"`
-	Id2           int32                  `protobuf:"varint,4,opt,name=id2,proto3" json:"id2,omitempty"`
-	Timestamp     string                 `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // RFC3339
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Value      float64                `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	SensorType string                 `protobuf:"bytes,2,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
+	Id1        string                 `protobuf:"bytes,3,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2        int32                  `protobuf:"varint,4,opt,name=id2,proto3" json:"id2,omitempty"`
+	Timestamp  string                 `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // RFC3339
+	Unit       string                 `protobuf:"bytes,6,opt,name=unit,proto3" json:"unit,omitempty"`
+	// reading_id is a client-generated idempotency key; readings re-sent with
+	// the same key are stored once. Empty keys are never deduplicated.
+	ReadingId     string `protobuf:"bytes,7,opt,name=reading_id,json=readingId,proto3" json:"reading_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -97,16 +150,138 @@ func (x *Reading) GetTimestamp() string {
 	return ""
 }
 
-type WriteAck struct {
+func (x *Reading) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *Reading) GetReadingId() string {
+	if x != nil {
+		return x.ReadingId
+	}
+	return ""
+}
+
+type ReadingBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Readings      []*Reading             `protobuf:"bytes,1,rep,name=readings,proto3" json:"readings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadingBatch) Reset() {
+	*x = ReadingBatch{}
+	mi := &file_proto_ingest_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadingBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadingBatch) ProtoMessage() {}
+
+func (x *ReadingBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ingest_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadingBatch.ProtoReflect.Descriptor instead.
+func (*ReadingBatch) Descriptor() ([]byte, []int) {
+	return file_proto_ingest_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReadingBatch) GetReadings() []*Reading {
+	if x != nil {
+		return x.Readings
+	}
+	return nil
+}
+
+// Rejection reports a reading that failed validation and was not stored.
+type Rejection struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Count         uint64                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Index         uint64                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"` // position of the reading in the stream, or in the request for Ingest
+	ReadingId     string                 `protobuf:"bytes,2,opt,name=reading_id,json=readingId,proto3" json:"reading_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rejection) Reset() {
+	*x = Rejection{}
+	mi := &file_proto_ingest_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rejection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rejection) ProtoMessage() {}
+
+func (x *Rejection) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ingest_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rejection.ProtoReflect.Descriptor instead.
+func (*Rejection) Descriptor() ([]byte, []int) {
+	return file_proto_ingest_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Rejection) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Rejection) GetReadingId() string {
+	if x != nil {
+		return x.ReadingId
+	}
+	return ""
+}
+
+func (x *Rejection) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type WriteAck struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Count    uint64                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Rejected uint64                 `protobuf:"varint,2,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	// rejections lists the first rejected readings; rejected counts them all.
+	Rejections    []*Rejection `protobuf:"bytes,3,rep,name=rejections,proto3" json:"rejections,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *WriteAck) Reset() {
 	*x = WriteAck{}
-	mi := &file_proto_ingest_proto_msgTypes[1]
+	mi := &file_proto_ingest_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -118,7 +293,7 @@ func (x *WriteAck) String() string {
 func (*WriteAck) ProtoMessage() {}
 
 func (x *WriteAck) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_ingest_proto_msgTypes[1]
+	mi := &file_proto_ingest_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -131,7 +306,7 @@ func (x *WriteAck) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WriteAck.ProtoReflect.Descriptor instead.
 func (*WriteAck) Descriptor() ([]byte, []int) {
-	return file_proto_ingest_proto_rawDescGZIP(), []int{1}
+	return file_proto_ingest_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *WriteAck) GetCount() uint64 {
@@ -141,22 +316,211 @@ func (x *WriteAck) GetCount() uint64 {
 	return 0
 }
 
+func (x *WriteAck) GetRejected() uint64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *WriteAck) GetRejections() []*Rejection {
+	if x != nil {
+		return x.Rejections
+	}
+	return nil
+}
+
+// IngestRequest carries a batch of readings under a sequence number that is
+// unique within the stream.
+type IngestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Readings      []*Reading             `protobuf:"bytes,2,rep,name=readings,proto3" json:"readings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestRequest) Reset() {
+	*x = IngestRequest{}
+	mi := &file_proto_ingest_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestRequest) ProtoMessage() {}
+
+func (x *IngestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ingest_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestRequest.ProtoReflect.Descriptor instead.
+func (*IngestRequest) Descriptor() ([]byte, []int) {
+	return file_proto_ingest_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *IngestRequest) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *IngestRequest) GetReadings() []*Reading {
+	if x != nil {
+		return x.Readings
+	}
+	return nil
+}
+
+type IngestAck struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Seq    uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Status IngestAck_Status       `protobuf:"varint,2,opt,name=status,proto3,enum=ingest.IngestAck_Status" json:"status,omitempty"`
+	Reason string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Count  uint64                 `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	// Readings that failed validation are rejected individually; the rest
+	// of the request is still stored.
+	Rejected      uint64       `protobuf:"varint,5,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Rejections    []*Rejection `protobuf:"bytes,6,rep,name=rejections,proto3" json:"rejections,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestAck) Reset() {
+	*x = IngestAck{}
+	mi := &file_proto_ingest_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestAck) ProtoMessage() {}
+
+func (x *IngestAck) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ingest_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestAck.ProtoReflect.Descriptor instead.
+func (*IngestAck) Descriptor() ([]byte, []int) {
+	return file_proto_ingest_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IngestAck) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *IngestAck) GetStatus() IngestAck_Status {
+	if x != nil {
+		return x.Status
+	}
+	return IngestAck_STORED
+}
+
+func (x *IngestAck) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *IngestAck) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *IngestAck) GetRejected() uint64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *IngestAck) GetRejections() []*Rejection {
+	if x != nil {
+		return x.Rejections
+	}
+	return nil
+}
+
 var File_proto_ingest_proto protoreflect.FileDescriptor
 
 const file_proto_ingest_proto_rawDesc = "" +
 	"\n" +
-	"\x12proto/ingest.proto\x12\x06ingest\"\x82\x01\n" +
+	"\x12proto/ingest.proto\x12\x06ingest\"\xb5\x01\n" +
 	"\aReading\x12\x14\n" +
 	"\x05value\x18\x01 \x01(\x01R\x05value\x12\x1f\n" +
 	"\vsensor_type\x18\x02 \x01(\tR\n" +
 	"sensorType\x12\x10\n" +
 	"\x03id1\x18\x03 \x01(\tR\x03id1\x12\x10\n" +
 	"\x03id2\x18\x04 \x01(\x05R\x03id2\x12\x1c\n" +
-	"\ttimestamp\x18\x05 \x01(\tR\ttimestamp\" \n" +
+	"\ttimestamp\x18\x05 \x01(\tR\ttimestamp\x12\x12\n" +
+	"\x04unit\x18\x06 \x01(\tR\x04unit\x12\x1d\n" +
+	"\n" +
+	"reading_id\x18\a \x01(\tR\treadingId\";\n" +
+	"\fReadingBatch\x12+\n" +
+	"\breadings\x18\x01 \x03(\v2\x0f.ingest.ReadingR\breadings\"X\n" +
+	"\tRejection\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\x12\x1d\n" +
+	"\n" +
+	"reading_id\x18\x02 \x01(\tR\treadingId\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"o\n" +
 	"\bWriteAck\x12\x14\n" +
-	"\x05count\x18\x01 \x01(\x04R\x05count2=\n" +
+	"\x05count\x18\x01 \x01(\x04R\x05count\x12\x1a\n" +
+	"\brejected\x18\x02 \x01(\x04R\brejected\x121\n" +
+	"\n" +
+	"rejections\x18\x03 \x03(\v2\x11.ingest.RejectionR\n" +
+	"rejections\"N\n" +
+	"\rIngestRequest\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12+\n" +
+	"\breadings\x18\x02 \x03(\v2\x0f.ingest.ReadingR\breadings\"\xfc\x01\n" +
+	"\tIngestAck\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x120\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x18.ingest.IngestAck.StatusR\x06status\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x04R\x05count\x12\x1a\n" +
+	"\brejected\x18\x05 \x01(\x04R\brejected\x121\n" +
+	"\n" +
+	"rejections\x18\x06 \x03(\v2\x11.ingest.RejectionR\n" +
+	"rejections\".\n" +
+	"\x06Status\x12\n" +
+	"\n" +
+	"\x06STORED\x10\x00\x12\f\n" +
+	"\bREJECTED\x10\x01\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x022\xad\x01\n" +
 	"\rIngestService\x12,\n" +
-	"\x05Write\x12\x0f.ingest.Reading\x1a\x10.ingest.WriteAck(\x01B8Z6github.com/glitchdawg/synthetic_sensors/proto/ingestpbb\x06proto3"
+	"\x05Write\x12\x0f.ingest.Reading\x1a\x10.ingest.WriteAck(\x01\x126\n" +
+	"\n" +
+	"WriteBatch\x12\x14.ingest.ReadingBatch\x1a\x10.ingest.WriteAck(\x01\x126\n" +
+	"\x06Ingest\x12\x15.ingest.IngestRequest\x1a\x11.ingest.IngestAck(\x010\x01B8Z6github.com/glitchdawg/synthetic_sensors/proto/ingestpbb\x06proto3"
 
 var (
 	file_proto_ingest_proto_rawDescOnce sync.Once
@@ -170,19 +534,34 @@ func file_proto_ingest_proto_rawDescGZIP() []byte {
 	return file_proto_ingest_proto_rawDescData
 }
 
-var file_proto_ingest_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_ingest_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_ingest_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_ingest_proto_goTypes = []any{
-	(*Reading)(nil),  // 0: ingest.Reading
-	(*WriteAck)(nil), // 1: ingest.WriteAck
+	(IngestAck_Status)(0), // 0: ingest.IngestAck.Status
+	(*Reading)(nil),       // 1: ingest.Reading
+	(*ReadingBatch)(nil),  // 2: ingest.ReadingBatch
+	(*Rejection)(nil),     // 3: ingest.Rejection
+	(*WriteAck)(nil),      // 4: ingest.WriteAck
+	(*IngestRequest)(nil), // 5: ingest.IngestRequest
+	(*IngestAck)(nil),     // 6: ingest.IngestAck
 }
 var file_proto_ingest_proto_depIdxs = []int32{
-	0, // 0: ingest.IngestService.Write:input_type -> ingest.Reading
-	1, // 1: ingest.IngestService.Write:output_type -> ingest.WriteAck
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: ingest.ReadingBatch.readings:type_name -> ingest.Reading
+	3, // 1: ingest.WriteAck.rejections:type_name -> ingest.Rejection
+	1, // 2: ingest.IngestRequest.readings:type_name -> ingest.Reading
+	0, // 3: ingest.IngestAck.status:type_name -> ingest.IngestAck.Status
+	3, // 4: ingest.IngestAck.rejections:type_name -> ingest.Rejection
+	1, // 5: ingest.IngestService.Write:input_type -> ingest.Reading
+	2, // 6: ingest.IngestService.WriteBatch:input_type -> ingest.ReadingBatch
+	5, // 7: ingest.IngestService.Ingest:input_type -> ingest.IngestRequest
+	4, // 8: ingest.IngestService.Write:output_type -> ingest.WriteAck
+	4, // 9: ingest.IngestService.WriteBatch:output_type -> ingest.WriteAck
+	6, // 10: ingest.IngestService.Ingest:output_type -> ingest.IngestAck
+	8, // [8:11] is the sub-list for method output_type
+	5, // [5:8] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_proto_ingest_proto_init() }
@@ -195,13 +574,14 @@ func file_proto_ingest_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_ingest_proto_rawDesc), len(file_proto_ingest_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_ingest_proto_goTypes,
 		DependencyIndexes: file_proto_ingest_proto_depIdxs,
+		EnumInfos:         file_proto_ingest_proto_enumTypes,
 		MessageInfos:      file_proto_ingest_proto_msgTypes,
 	}.Build()
 	File_proto_ingest_proto = out.File
@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	IngestService_Write_FullMethodName = "/ingest.IngestService/Write"
+	IngestService_Write_FullMethodName      = "/ingest.IngestService/Write"
+	IngestService_WriteBatch_FullMethodName = "/ingest.IngestService/WriteBatch"
+	IngestService_Ingest_FullMethodName     = "/ingest.IngestService/Ingest"
 )
 
 // IngestServiceClient is the client API for IngestService service.
@@ -27,6 +29,11 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type IngestServiceClient interface {
 	Write(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Reading, WriteAck], error)
+	// WriteBatch persists each batch in a single database round trip.
+	WriteBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ReadingBatch, WriteAck], error)
+	// Ingest acknowledges every request by sequence number as soon as it has
+	// been stored, so the client knows what to retry.
+	Ingest(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[IngestRequest, IngestAck], error)
 }
 
 type ingestServiceClient struct {
@@ -50,11 +57,42 @@ func (c *ingestServiceClient) Write(ctx context.Context, opts ...grpc.CallOption
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type IngestService_WriteClient = grpc.ClientStreamingClient[Reading, WriteAck]
 
+func (c *ingestServiceClient) WriteBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ReadingBatch, WriteAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IngestService_ServiceDesc.Streams[1], IngestService_WriteBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReadingBatch, WriteAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IngestService_WriteBatchClient = grpc.ClientStreamingClient[ReadingBatch, WriteAck]
+
+func (c *ingestServiceClient) Ingest(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[IngestRequest, IngestAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IngestService_ServiceDesc.Streams[2], IngestService_Ingest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[IngestRequest, IngestAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IngestService_IngestClient = grpc.BidiStreamingClient[IngestRequest, IngestAck]
+
 // IngestServiceServer is the server API for IngestService service.
 // All implementations must embed UnimplementedIngestServiceServer
 // for forward compatibility.
 type IngestServiceServer interface {
 	Write(grpc.ClientStreamingServer[Reading, WriteAck]) error
+	// WriteBatch persists each batch in a single database round trip.
+	WriteBatch(grpc.ClientStreamingServer[ReadingBatch, WriteAck]) error
+	// Ingest acknowledges every request by sequence number as soon as it has
+	// been stored, so the client knows what to retry.
+	Ingest(grpc.BidiStreamingServer[IngestRequest, IngestAck]) error
 	mustEmbedUnimplementedIngestServiceServer()
 }
 
@@ -68,6 +106,12 @@ type UnimplementedIngestServiceServer struct{}
 func (UnimplementedIngestServiceServer) Write(grpc.ClientStreamingServer[Reading, WriteAck]) error {
 	return status.Errorf(codes.Unimplemented, "method Write not implemented")
 }
+func (UnimplementedIngestServiceServer) WriteBatch(grpc.ClientStreamingServer[ReadingBatch, WriteAck]) error {
+	return status.Errorf(codes.Unimplemented, "method WriteBatch not implemented")
+}
+func (UnimplementedIngestServiceServer) Ingest(grpc.BidiStreamingServer[IngestRequest, IngestAck]) error {
+	return status.Errorf(codes.Unimplemented, "method Ingest not implemented")
+}
 func (UnimplementedIngestServiceServer) mustEmbedUnimplementedIngestServiceServer() {}
 func (UnimplementedIngestServiceServer) testEmbeddedByValue()                       {}
 
@@ -96,6 +140,20 @@ func _IngestService_Write_Handler(srv interface{}, stream grpc.ServerStream) err
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type IngestService_WriteServer = grpc.ClientStreamingServer[Reading, WriteAck]
 
+func _IngestService_WriteBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IngestServiceServer).WriteBatch(&grpc.GenericServerStream[ReadingBatch, WriteAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IngestService_WriteBatchServer = grpc.ClientStreamingServer[ReadingBatch, WriteAck]
+
+func _IngestService_Ingest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IngestServiceServer).Ingest(&grpc.GenericServerStream[IngestRequest, IngestAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IngestService_IngestServer = grpc.BidiStreamingServer[IngestRequest, IngestAck]
+
 // IngestService_ServiceDesc is the grpc.ServiceDesc for IngestService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -109,6 +167,17 @@ var IngestService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _IngestService_Write_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "WriteBatch",
+			Handler:       _IngestService_WriteBatch_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Ingest",
+			Handler:       _IngestService_Ingest_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "proto/ingest.proto",
 }
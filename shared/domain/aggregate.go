@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AggregateBuckets are the supported bucket sizes, by name.
+var AggregateBuckets = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// AggregateFunctions are the supported aggregate functions besides
+// percentiles, which are written pNN (p50, p95, p99.9).
+var AggregateFunctions = []string{"avg", "min", "max", "sum", "count", "stddev", "first", "last"}
+
+// AggregateGroupBy are the columns readings can be grouped by.
+var AggregateGroupBy = []string{"id1", "id2", "sensor_type"}
+
+// ParsePercentile returns the fraction requested by a pNN function name.
+func ParsePercentile(fn string) (float64, bool) {
+	if !strings.HasPrefix(fn, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(fn[1:], 64)
+	// NaN passes any range check, so it is ruled out first.
+	if err != nil || math.IsNaN(p) || math.IsInf(p, 0) || p <= 0 || p >= 100 {
+		return 0, false
+	}
+	return p / 100, true
+}
+
+type AggregateQuery struct {
+	Filter    SensorReadingFilter // ID and time range fields only
+	Bucket    string              // a key of AggregateBuckets
+	Functions []string
+	GroupBy   []string
+}
+
+type AggregateBucket struct {
+	Bucket     time.Time           `json:"bucket" example:"2024-01-15T10:00:00Z"`       // Start of the bucket
+	ID1        *string             `json:"id1,omitempty" example:"A"`                   // Set when grouped by id1
+	ID2        *int                `json:"id2,omitempty" example:"42"`                  // Set when grouped by id2
+	SensorType *string             `json:"sensor_type,omitempty" example:"temperature"` // Set when grouped by sensor_type
+	Values     map[string]*float64 `json:"values" swaggertype:"object,number"`          // Result of each function; null when undefined, e.g. stddev of one reading
+}
+
+type AggregateResult struct {
+	Bucket    string            `json:"bucket" example:"1h"`
+	Functions []string          `json:"functions" example:"avg,max,p95"`
+	GroupBy   []string          `json:"group_by,omitempty" example:"id1"`
+	Data      []AggregateBucket `json:"data"`
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePercentile(t *testing.T) {
+	for _, tt := range []struct {
+		fn   string
+		want float64
+		ok   bool
+	}{
+		{"p50", 0.5, true},
+		{"p95", 0.95, true},
+		{"p99.9", 0.999, true},
+		{"p0", 0, false},
+		{"p100", 0, false},
+		{"p-5", 0, false},
+		{"pNaN", 0, false},
+		{"pnan", 0, false},
+		{"pInf", 0, false},
+		{"p+Inf", 0, false},
+		{"p", 0, false},
+		{"p1e1", 0.1, true},
+		{"avg", 0, false},
+		{"50", 0, false},
+	} {
+		got, ok := ParsePercentile(tt.fn)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("ParsePercentile(%q) = %v, %v, want %v, %v", tt.fn, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -6,11 +6,13 @@ import (
 
 type SensorReading struct {
 	ID         int       `json:"id" db:"id" example:"1"`                                                        // Unique identifier
-	ID1        string    `json:"id1" db:"id1" validate:"required,alpha,uppercase" example:"A"`                 // First identifier (A-Z)
-	ID2        int       `json:"id2" db:"id2" validate:"required,min=0,max=999" example:"42"`                  // Second identifier (0-999)
-	SensorType string    `json:"sensor_type" db:"sensor_type" validate:"required" example:"temperature"`       // Type of sensor
-	Value      float64   `json:"value" db:"value" validate:"required" example:"23.5"`                          // Sensor reading value
+	ID1        string    `json:"id1" db:"id1" validate:"required,alpha,uppercase,max=10" example:"A"`          // First identifier (A-Z)
+	ID2        int       `json:"id2" db:"id2" validate:"min=0,max=999" example:"42"`                           // Second identifier (0-999)
+	SensorType string    `json:"sensor_type" db:"sensor_type" validate:"required,max=50" example:"temperature"`    // Type of sensor
+	Value      float64   `json:"value" db:"value" example:"23.5"`                                              // Sensor reading value
+	Unit       string    `json:"unit" db:"unit" validate:"max=20" example:"°C"`                                // Unit of the value
 	Timestamp  time.Time `json:"timestamp" db:"ts" example:"2024-01-15T10:30:00Z"`                             // When reading was taken
+	ReadingID  *string   `json:"reading_id,omitempty" db:"reading_id" validate:"omitempty,max=64" example:"9f1c2a7e4b3d8e01-42"` // Client-generated idempotency key; re-sent readings are stored once
 }
 
 type SensorReadingFilter struct {